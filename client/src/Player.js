@@ -2,6 +2,7 @@ import React from "react";
 import Card from "./react-poker/Card.js";
 
 const TABLE_STATUS_DONE = 2;
+const VARIANT_OMAHA_HI = 1;
 
 const intToDollars = (i) => {
 	const dollars = i / 100;
@@ -17,7 +18,7 @@ export default function Player(props) {
 		? props.player.Cards
 		: props.table.Seats[props.seat].Cards
 		? props.table.Seats[props.seat].Cards
-		: ["Xx", "Xx"];
+		: Array(props.table.Options.Variant === VARIANT_OMAHA_HI ? 4 : 2).fill("Xx");
 	const roundOver = props.table.Status === TABLE_STATUS_DONE;
 
 	return (
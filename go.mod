@@ -9,3 +9,5 @@ require (
 )
 
 require github.com/stretchr/testify v1.11.1 // indirect
+
+replace github.com/alcamerone/joker => ./joker
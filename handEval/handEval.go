@@ -0,0 +1,31 @@
+/*    package "handEval" evaluates poker hands for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handEval
+
+import (
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+)
+
+// BestHand returns the best hand a player can make from their hole cards and
+// the board under the rules of the given variant. In Omaha, a hand must use
+// exactly two hole cards and three cards from the board.
+func BestHand(variant table.Variant, holeCards []hand.Card, board []hand.Card) *hand.Hand {
+	return table.BestHand(variant, holeCards, board)
+}
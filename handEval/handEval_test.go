@@ -0,0 +1,79 @@
+/*    package "handEval" evaluates poker hands for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package handEval
+
+import (
+	"testing"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/jokertest"
+	"github.com/alcamerone/joker/table"
+)
+
+func TestBestHand(t *testing.T) {
+	board := jokertest.Cards("Qh", "Jh", "Th", "3h", "2s")
+	tests := []struct {
+		name    string
+		variant table.Variant
+		hole    []hand.Card
+		board   []hand.Card
+		want    hand.Ranking
+	}{
+		{
+			name:  "hold'em uses one hole card",
+			hole:  jokertest.Cards("Ah", "7d"),
+			board: board,
+			want:  hand.Flush,
+		},
+		{
+			name:    "omaha needs two hole cards of the suit",
+			variant: table.OmahaHi,
+			hole:    jokertest.Cards("Ah", "7d", "7c", "5s"),
+			board:   board,
+			want:    hand.Pair,
+		},
+		{
+			name:    "omaha uses exactly two hole cards",
+			variant: table.OmahaHi,
+			hole:    jokertest.Cards("Ah", "Kh", "Ac", "Ad"),
+			board:   board,
+			want:    hand.RoyalFlush,
+		},
+		{
+			name:    "omaha can't play three of a kind from the hole",
+			variant: table.OmahaHi,
+			hole:    jokertest.Cards("9c", "9d", "9s", "4c"),
+			board:   jokertest.Cards("Ks", "8h", "5d", "2c", "2d"),
+			want:    hand.TwoPair,
+		},
+		{
+			name:    "omaha before the flop",
+			variant: table.OmahaHi,
+			hole:    jokertest.Cards("Ah", "Ad", "Kc", "Ks"),
+			want:    hand.TwoPair,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BestHand(tt.variant, tt.hole, tt.board).Ranking(); got != tt.want {
+				t.Errorf("BestHand() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
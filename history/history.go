@@ -0,0 +1,331 @@
+/*    package "history" records hand histories for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/handEval"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// nextHandId is shared between all recorders so that hand IDs are unique
+// across rooms
+var nextHandId = time.Now().Unix() * 1000
+
+type Hand struct {
+	Id         int64
+	RoomId     string
+	Started    time.Time
+	Seed       []byte
+	Options    table.Options
+	Seats      []Seat
+	Button     string
+	SmallBlind string
+	BigBlind   string
+	Actions    []Action
+	Board      []hand.Card
+	// Boards holds every board, starting with Board, if the hand was run
+	// more than once
+	Boards   [][]hand.Card `json:",omitempty"`
+	Showdown []Showdown
+	Winnings []Winning
+	Pot      int
+	// Antes is the ante each player posted, which is not always the ante in
+	// Options if one player posted it on everyone's behalf
+	Antes map[string]int `json:",omitempty"`
+	// Limit is the betting limit the hand was played at. The table in
+	// Options has no fixed-limit setting, since the server enforces it, so
+	// SmallBet and BigBet hold the bet sizes of a fixed-limit hand.
+	Limit    types.Limit
+	SmallBet int `json:",omitempty"`
+	BigBet   int `json:",omitempty"`
+}
+
+type Seat struct {
+	PlayerId string
+	Seat     int
+	Chips    int
+}
+
+type Action struct {
+	table.Action
+	PlayerId string
+	Round    table.Round
+	// Added is the number of chips the action put into the pot, and
+	// RoundTotal the player's total contribution in this betting round
+	Added      int
+	RoundTotal int
+	AllIn      bool
+}
+
+type Showdown struct {
+	PlayerId    string
+	Cards       []hand.Card
+	Description string
+	Mucked      bool `json:",omitempty"`
+}
+
+type Winning struct {
+	PlayerId string
+	Amount   int
+}
+
+// Recorder records the hands played at a single table, keeping up to
+// maxHands of the most recent ones.
+type Recorder struct {
+	sync.RWMutex
+	roomId   string
+	maxHands int
+	hands    []Hand
+	current  *Hand
+	// inPot tracks each player's total contribution to the current pot, and
+	// roundStart their contribution at the start of the current betting round
+	inPot      map[string]int
+	roundStart map[string]int
+	round      table.Round
+}
+
+func NewRecorder(roomId string, maxHands int) *Recorder {
+	return &Recorder{roomId: roomId, maxHands: maxHands}
+}
+
+// StartHand begins recording a new hand from the table state immediately
+// after the deal, once blinds and antes have been posted, and the seed the
+// deck was shuffled with.
+func (r *Recorder) StartHand(state table.State, seed []byte) {
+	r.Lock()
+	defer r.Unlock()
+	h := &Hand{
+		Id:         atomic.AddInt64(&nextHandId, 1),
+		RoomId:     r.roomId,
+		Started:    time.Now(),
+		Seed:       seed,
+		Options:    state.Options,
+		Button:     state.Dealer.ID,
+		SmallBlind: state.SmallBlind.ID,
+		BigBlind:   state.BigBlind.ID,
+	}
+	r.inPot = make(map[string]int, len(state.Seats))
+	r.roundStart = make(map[string]int, len(state.Seats))
+	r.round = state.Round
+	for i, seat := range state.Seats {
+		if seat.SittingOut {
+			continue
+		}
+		h.Seats = append(h.Seats, Seat{
+			PlayerId: seat.ID,
+			Seat:     i + 1,
+			Chips:    seat.Chips + seat.ChipsInPot,
+		})
+		r.inPot[seat.ID] = seat.ChipsInPot
+		// Antes are dead money, so don't count towards the pre-flop round
+		r.roundStart[seat.ID] = minInt(seat.ChipsInPot, state.Options.Stakes.Ante)
+	}
+	r.current = h
+}
+
+// RecordAction records an action taken by a player, given the table states
+// from immediately before and after it. If the action ended the hand, the
+// hand is completed and stored.
+func (r *Recorder) RecordAction(
+	playerId string,
+	action table.Action,
+	before table.State,
+	after table.State,
+) {
+	r.Lock()
+	defer r.Unlock()
+	if r.current == nil {
+		return
+	}
+	if before.Round != r.round {
+		r.round = before.Round
+		for id, chips := range r.inPot {
+			r.roundStart[id] = chips
+		}
+	}
+	seat, _ := findSeat(playerId, before)
+	added := contribution(action, before, seat.Chips)
+	r.inPot[playerId] += added
+	if action.Type == table.Straddle {
+		// A straddle raises by the big blind, though the action doesn't say
+		action.Chips = before.Options.Stakes.BigBlind
+	}
+	r.current.Actions = append(r.current.Actions, Action{
+		Action:     action,
+		PlayerId:   playerId,
+		Round:      before.Round,
+		Added:      added,
+		RoundTotal: r.inPot[playerId] - r.roundStart[playerId],
+		AllIn:      added > 0 && added == seat.Chips,
+	})
+	if after.Status == table.Done {
+		r.endHand(after)
+	}
+}
+
+// contribution works out how many chips an action put into the pot, given
+// the state before it and how many chips the player had beforehand.
+func contribution(action table.Action, before table.State, chips int) int {
+	var added int
+	owed := before.Owed
+	switch action.Type {
+	case table.Call:
+		added = owed
+	case table.Bet, table.Raise:
+		added = owed + action.Chips
+	case table.Straddle:
+		added = owed + before.Options.Stakes.BigBlind
+	case table.AllIn:
+		added = chips
+	}
+	return minInt(added, chips)
+}
+
+// winnings works out what each player took from the pot by comparing their
+// final stack with their starting stack less their contribution.
+func (r *Recorder) winnings(state table.State) []Winning {
+	winnings := make([]Winning, 0)
+	for _, s := range r.current.Seats {
+		seat, ok := findSeat(s.PlayerId, state)
+		if !ok {
+			continue
+		}
+		won := seat.Chips - (s.Chips - r.inPot[s.PlayerId])
+		if won > 0 {
+			winnings = append(winnings, Winning{PlayerId: s.PlayerId, Amount: won})
+		}
+	}
+	return winnings
+}
+
+// endHand must be called with the lock held
+func (r *Recorder) endHand(state table.State) {
+	h := r.current
+	h.Board = state.Result.TableCards
+	for _, chips := range r.inPot {
+		h.Pot += chips
+	}
+	if len(state.Result.Contestants) > 1 {
+		for _, c := range state.Result.Contestants {
+			h.Showdown = append(h.Showdown, Showdown{
+				PlayerId: c.ID,
+				Cards:    c.Cards,
+				Description: handEval.BestHand(
+					h.Options.Variant,
+					c.Cards,
+					h.Board,
+				).Description(),
+			})
+		}
+	}
+	h.Winnings = r.winnings(state)
+	r.hands = append(r.hands, *h)
+	if r.maxHands > 0 && len(r.hands) > r.maxHands {
+		r.hands = r.hands[len(r.hands)-r.maxHands:]
+	}
+	r.current = nil
+}
+
+// RecordAntes records the antes posted in the hand being recorded, and must
+// be called straight after StartHand. Antes don't count towards the pre-flop
+// betting round.
+func (r *Recorder) RecordAntes(antes map[string]int) {
+	r.Lock()
+	defer r.Unlock()
+	if r.current == nil || len(antes) == 0 {
+		return
+	}
+	r.current.Antes = antes
+	for id := range r.roundStart {
+		r.roundStart[id] = antes[id]
+	}
+}
+
+// RecordLimit records the betting limit of the hand being recorded, and must
+// be called straight after StartHand.
+func (r *Recorder) RecordLimit(limit types.Limit, smallBet int, bigBet int) {
+	r.Lock()
+	defer r.Unlock()
+	if r.current == nil {
+		return
+	}
+	r.current.Limit = limit
+	if limit == types.LimitFixedLimit {
+		r.current.SmallBet = smallBet
+		r.current.BigBet = bigBet
+	}
+}
+
+// RecordRunouts amends the last hand recorded once its board has been run
+// more than once, given every board and the chips each player won in total.
+func (r *Recorder) RecordRunouts(boards [][]hand.Card, won map[string]int) {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.hands) == 0 {
+		return
+	}
+	h := &r.hands[len(r.hands)-1]
+	h.Boards = boards
+	h.Winnings = make([]Winning, 0, len(won))
+	for _, s := range h.Seats {
+		if won[s.PlayerId] > 0 {
+			h.Winnings = append(h.Winnings, Winning{PlayerId: s.PlayerId, Amount: won[s.PlayerId]})
+		}
+	}
+}
+
+// RecordShowdown amends the last hand recorded with the cards actually shown
+// at the showdown, replacing every contestant's cards.
+func (r *Recorder) RecordShowdown(showdown []Showdown) {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.hands) == 0 {
+		return
+	}
+	r.hands[len(r.hands)-1].Showdown = showdown
+}
+
+// Hands returns the completed hands, oldest first.
+func (r *Recorder) Hands() []Hand {
+	r.RLock()
+	defer r.RUnlock()
+	return append([]Hand(nil), r.hands...)
+}
+
+func findSeat(playerId string, state table.State) (table.Player, bool) {
+	for _, seat := range state.Seats {
+		if seat.ID == playerId {
+			return seat, true
+		}
+	}
+	return table.Player{}, false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,240 @@
+/*    package "history" records hand histories for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+const POKERSTARS_TIME_FORMAT = "2006/01/02 15:04:05 MST"
+
+var pokerStarsSuits = []string{"s", "h", "d", "c"}
+
+// WritePokerStars writes the given hands in the PokerStars hand history text
+// format, which most hand tracking tools can import.
+func WritePokerStars(w io.Writer, hands []Hand) error {
+	bw := bufio.NewWriter(w)
+	for _, h := range hands {
+		writePokerStarsHand(bw, h)
+		fmt.Fprint(bw, "\n\n\n")
+	}
+	return bw.Flush()
+}
+
+func writePokerStarsHand(w io.Writer, h Hand) {
+	// Fixed-limit hands are labelled with their bet sizes instead of blinds
+	small, big := h.Options.Stakes.SmallBlind, h.Options.Stakes.BigBlind
+	if h.Limit == types.LimitFixedLimit {
+		small, big = h.SmallBet, h.BigBet
+	}
+	fmt.Fprintf(
+		w,
+		"PokerStars Hand #%d:  %s %s (%d/%d) - %s\n",
+		h.Id,
+		pokerStarsVariant(h.Options.Variant),
+		pokerStarsLimit(h.Limit),
+		small,
+		big,
+		h.Started.UTC().Format(POKERSTARS_TIME_FORMAT))
+	fmt.Fprintf(
+		w,
+		"Table '%s' %d-max Seat #%d is the button\n",
+		h.RoomId,
+		len(h.Seats),
+		h.seatNumber(h.Button))
+	for _, s := range h.Seats {
+		fmt.Fprintf(w, "Seat %d: %s (%d in chips)\n", s.Seat, s.PlayerId, s.Chips)
+	}
+	if h.Antes != nil {
+		for _, s := range h.Seats {
+			if h.Antes[s.PlayerId] > 0 {
+				fmt.Fprintf(w, "%s: posts the ante %d\n", s.PlayerId, h.Antes[s.PlayerId])
+			}
+		}
+	} else if h.Options.Stakes.Ante > 0 {
+		for _, s := range h.Seats {
+			fmt.Fprintf(w, "%s: posts the ante %d\n", s.PlayerId, minInt(h.Options.Stakes.Ante, s.Chips))
+		}
+	}
+	fmt.Fprintf(w, "%s: posts small blind %d\n", h.SmallBlind, h.Options.Stakes.SmallBlind)
+	fmt.Fprintf(w, "%s: posts big blind %d\n", h.BigBlind, h.Options.Stakes.BigBlind)
+	fmt.Fprintln(w, "*** HOLE CARDS ***")
+
+	round := table.PreFlop
+	for _, a := range h.Actions {
+		for round < a.Round {
+			round++
+			writePokerStarsStreet(w, round, h.Board)
+		}
+		fmt.Fprintln(w, pokerStarsAction(a))
+	}
+	if len(h.Showdown) > 0 {
+		for round < table.River {
+			round++
+			writePokerStarsStreet(w, round, h.Board)
+		}
+		fmt.Fprintln(w, "*** SHOW DOWN ***")
+		for _, s := range h.Showdown {
+			if s.Mucked {
+				fmt.Fprintf(w, "%s: mucks hand\n", s.PlayerId)
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"%s: shows %s (%s)\n",
+				s.PlayerId,
+				pokerStarsCards(s.Cards),
+				s.Description)
+		}
+	}
+	for _, win := range h.Winnings {
+		fmt.Fprintf(w, "%s collected %d from pot\n", win.PlayerId, win.Amount)
+	}
+
+	fmt.Fprintln(w, "*** SUMMARY ***")
+	fmt.Fprintf(w, "Total pot %d | Rake 0\n", h.Pot)
+	if len(h.Board) > 0 {
+		fmt.Fprintf(w, "Board %s\n", pokerStarsCards(h.Board))
+	}
+	for _, s := range h.Seats {
+		fmt.Fprintf(w, "Seat %d: %s %s\n", s.Seat, s.PlayerId, h.summary(s.PlayerId))
+	}
+}
+
+func writePokerStarsStreet(w io.Writer, round table.Round, board []hand.Card) {
+	switch round {
+	case table.Flop:
+		if len(board) >= 3 {
+			fmt.Fprintf(w, "*** FLOP *** %s\n", pokerStarsCards(board[:3]))
+		}
+	case table.Turn:
+		if len(board) >= 4 {
+			fmt.Fprintf(
+				w,
+				"*** TURN *** %s %s\n",
+				pokerStarsCards(board[:3]),
+				pokerStarsCards(board[3:4]))
+		}
+	case table.River:
+		if len(board) >= 5 {
+			fmt.Fprintf(
+				w,
+				"*** RIVER *** %s %s\n",
+				pokerStarsCards(board[:4]),
+				pokerStarsCards(board[4:5]))
+		}
+	}
+}
+
+func pokerStarsAction(a Action) string {
+	var str string
+	switch a.Type {
+	case table.Fold:
+		return a.PlayerId + ": folds"
+	case table.Check:
+		return a.PlayerId + ": checks"
+	case table.Call:
+		str = fmt.Sprintf("%s: calls %d", a.PlayerId, a.Added)
+	case table.Bet:
+		str = fmt.Sprintf("%s: bets %d", a.PlayerId, a.Added)
+	case table.Raise, table.Straddle:
+		str = fmt.Sprintf("%s: raises %d to %d", a.PlayerId, a.Chips, a.RoundTotal)
+	case table.AllIn:
+		// An all-in is recorded as whichever action it amounted to
+		str = fmt.Sprintf("%s: raises %d to %d", a.PlayerId, a.Added, a.RoundTotal)
+		if a.RoundTotal == a.Added {
+			str = fmt.Sprintf("%s: bets %d", a.PlayerId, a.Added)
+		}
+	}
+	if a.AllIn {
+		str += " and is all-in"
+	}
+	return str
+}
+
+func pokerStarsCards(cards []hand.Card) string {
+	strs := make([]string, len(cards))
+	for i, c := range cards {
+		strs[i] = c.Rank().String() + pokerStarsSuits[c.Suit()]
+	}
+	return "[" + strings.Join(strs, " ") + "]"
+}
+
+func pokerStarsVariant(v table.Variant) string {
+	if v == table.OmahaHi {
+		return "Omaha"
+	}
+	return "Hold'em"
+}
+
+func pokerStarsLimit(l types.Limit) string {
+	switch l {
+	case types.LimitPotLimit:
+		return "Pot Limit"
+	case types.LimitFixedLimit:
+		return "Limit"
+	}
+	return "No Limit"
+}
+
+func (h Hand) seatNumber(playerId string) int {
+	for _, s := range h.Seats {
+		if s.PlayerId == playerId {
+			return s.Seat
+		}
+	}
+	return 0
+}
+
+func (h Hand) summary(playerId string) string {
+	for _, a := range h.Actions {
+		if a.PlayerId == playerId && a.Type == table.Fold {
+			return "folded"
+		}
+	}
+	var won int
+	for _, win := range h.Winnings {
+		if win.PlayerId == playerId {
+			won = win.Amount
+		}
+	}
+	for _, s := range h.Showdown {
+		if s.PlayerId != playerId {
+			continue
+		}
+		if s.Mucked {
+			return "mucked"
+		}
+		if won > 0 {
+			return fmt.Sprintf("showed %s and won (%d)", pokerStarsCards(s.Cards), won)
+		}
+		return fmt.Sprintf("showed %s and lost", pokerStarsCards(s.Cards))
+	}
+	if won > 0 {
+		return fmt.Sprintf("collected (%d)", won)
+	}
+	return "didn't play"
+}
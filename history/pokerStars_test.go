@@ -0,0 +1,82 @@
+/*    package "history" records hand histories for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestPokerStarsHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  table.Variant
+		limit    types.Limit
+		smallBet int
+		bigBet   int
+		want     string
+	}{
+		{
+			name: "no limit",
+			want: "PokerStars Hand #1:  Hold'em No Limit (1/2) - 2020/01/02 03:04:05 UTC",
+		},
+		{
+			name:    "pot limit omaha",
+			variant: table.OmahaHi,
+			limit:   types.LimitPotLimit,
+			want:    "PokerStars Hand #1:  Omaha Pot Limit (1/2) - 2020/01/02 03:04:05 UTC",
+		},
+		{
+			name:     "fixed limit shows the bet sizes",
+			limit:    types.LimitFixedLimit,
+			smallBet: 2,
+			bigBet:   4,
+			want:     "PokerStars Hand #1:  Hold'em Limit (2/4) - 2020/01/02 03:04:05 UTC",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Hand{
+				Id:      1,
+				Started: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				Options: table.Options{
+					Variant: tt.variant,
+					Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2},
+				},
+				Limit:    tt.limit,
+				SmallBet: tt.smallBet,
+				BigBet:   tt.bigBet,
+			}
+			var buf bytes.Buffer
+			writePokerStarsHand(&buf, h)
+			header, err := bufio.NewReader(&buf).ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header != tt.want+"\n" {
+				t.Errorf("header = %q; want %q", header, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/alcamerone/joker
+
+go 1.14
@@ -0,0 +1,908 @@
+package table
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/util"
+)
+
+type Status int
+
+const (
+	Broken Status = iota
+	Dealing
+	Done
+)
+
+type Round int
+
+const (
+	PreFlop Round = iota
+	Flop
+	Turn
+	River
+)
+
+type Variant int
+
+const (
+	TexasHoldem Variant = iota
+	OmahaHi
+)
+
+// HoleCards returns how many cards each player is dealt in the variant.
+func HoleCards(variant Variant) int {
+	if variant == OmahaHi {
+		return 4
+	}
+	return 2
+}
+
+// BestHand returns the best hand that can be made from the hole cards and
+// board. In Omaha it must use exactly two hole cards and three from the
+// board.
+func BestHand(variant Variant, holeCards []hand.Card, board []hand.Card) *hand.Hand {
+	if variant != OmahaHi || len(board) < 3 || len(holeCards) < 2 {
+		cards := append(append([]hand.Card(nil), holeCards...), board...)
+		return hand.New(cards)
+	}
+	var best *hand.Hand
+	for _, hole := range util.Combinations(len(holeCards), 2) {
+		for _, common := range util.Combinations(len(board), 3) {
+			cards := []hand.Card{holeCards[hole[0]], holeCards[hole[1]]}
+			for _, i := range common {
+				cards = append(cards, board[i])
+			}
+			h := hand.New(cards)
+			if best == nil || h.CompareTo(best) > 0 {
+				best = h
+			}
+		}
+	}
+	return best
+}
+
+type Limit int
+
+const (
+	NoLimit Limit = iota
+	PotLimit
+)
+
+type Options struct {
+	Buyin   int
+	Variant Variant
+	Stakes  Stakes
+	Limit   Limit
+	// OneShot stops the table once a hand is over, leaving its result in
+	// the state until NewRound is called. Otherwise the next hand is dealt
+	// straight away.
+	OneShot bool
+}
+
+type Stakes struct {
+	BigBlind   int
+	SmallBlind int
+	Ante       int
+	AnteType   AnteType
+}
+
+// AnteType is who posts the ante.
+type AnteType int
+
+const (
+	// ClassicAnte is posted by everyone dealt in.
+	ClassicAnte AnteType = iota
+	// BigBlindAnte is posted by the big blind on everyone's behalf, after
+	// the blind itself.
+	BigBlindAnte
+	// ButtonAnte is posted by the button on everyone's behalf.
+	ButtonAnte
+)
+
+type Table struct {
+	options Options
+	seats   []*Player
+	dealer  hand.Dealer
+	deck    *hand.Deck
+	cards   []hand.Card
+	active  *Player
+	status  Status
+	round   Round
+	button  int
+	sb      int
+	bb      int
+	cost    int
+	result  Result
+}
+
+// New seats the given players in order and deals the first hand. Players
+// who are sitting out are seated but not dealt in until they stop
+// defaulting.
+func New(dealer hand.Dealer, opts Options, playerIDs []string, sittingOut []string) *Table {
+	seats := []*Player{}
+	for _, id := range playerIDs {
+		p := &Player{
+			ID:         id,
+			Chips:      opts.Buyin,
+			defaulting: containsID(sittingOut, id),
+		}
+		seats = append(seats, p)
+	}
+	for i, seat := range seats {
+		seat.Seat = i
+	}
+	t := &Table{
+		options: opts,
+		seats:   seats,
+		dealer:  dealer,
+	}
+	t.setupHand()
+	return t
+}
+
+// Result describes how the last hand finished.
+type Result struct {
+	// Winners are the players who won chips from any pot
+	Winners []Player
+	// Contestants are the players who hadn't folded when the hand ended
+	Contestants []Player
+	TableCards  []hand.Card
+	// Pots are the main pot followed by any side pots
+	Pots []Pot
+	// Returned holds the uncalled part of the last bet or raise, which is
+	// handed back to the player who made it rather than put in a pot
+	Returned map[string]int
+}
+
+// Pot is a main or side pot as it was paid out.
+type Pot struct {
+	Chips      int
+	Contesting []string
+	// Winners are ordered from the first seat after the button, which is
+	// the order odd chips are handed out in
+	Winners []string
+	Shares  map[string]int
+}
+
+type State struct {
+	Options    Options
+	Seats      []Player
+	Cards      []hand.Card
+	Active     Player
+	Dealer     Player
+	SmallBlind Player
+	BigBlind   Player
+	Status     Status
+	Round      Round
+	Button     int
+	Cost       int
+	Owed       int
+	Pot        int
+	Result     Result
+}
+
+func (t *Table) State() State {
+	seats := []Player{}
+	pot := 0
+	for _, seat := range t.seats {
+		seats = append(seats, seat.copy())
+		pot += seat.ChipsInPot + seat.DeadChips
+	}
+	s := State{
+		Options: t.options,
+		Seats:   seats,
+		Cards:   append([]hand.Card(nil), t.cards...),
+		Button:  t.button,
+		Cost:    t.cost,
+		Round:   t.round,
+		Status:  t.status,
+		Pot:     pot,
+		Result:  t.result,
+	}
+	if t.active != nil {
+		s.Active = t.active.copy()
+		s.Owed = t.owed()
+	}
+	if t.status != Broken && len(t.seats) > 0 {
+		s.Dealer = t.seatAt(t.button)
+		s.SmallBlind = t.seatAt(t.sb)
+		s.BigBlind = t.seatAt(t.bb)
+	}
+	return s
+}
+
+type Action struct {
+	Type  ActionType
+	Chips int
+}
+
+type ActionType int
+
+const (
+	Fold ActionType = iota
+	Check
+	Call
+	Bet
+	Raise
+	AllIn
+	// Straddle is a blind raise of the big blind by the first player to act
+	// pre-flop, made before anyone has acted. Unlike a raise, it leaves the
+	// straddler the option of raising again if everyone just calls.
+	Straddle
+)
+
+func (t *Table) Fold() (State, error) {
+	return t.Act(Action{Type: Fold})
+}
+
+func (t *Table) Check() (State, error) {
+	return t.Act(Action{Type: Check})
+}
+
+func (t *Table) Call() (State, error) {
+	return t.Act(Action{Type: Call})
+}
+
+func (t *Table) Bet(chips int) (State, error) {
+	return t.Act(Action{Type: Bet, Chips: chips})
+}
+
+func (t *Table) Raise(chips int) (State, error) {
+	return t.Act(Action{Type: Raise, Chips: chips})
+}
+
+func (t *Table) AllIn() (State, error) {
+	return t.Act(Action{Type: AllIn})
+}
+
+func (t *Table) Straddle() (State, error) {
+	return t.Act(Action{Type: Straddle})
+}
+
+// Act applies an action by the active player and returns the state of the
+// table afterwards.
+func (t *Table) Act(a Action) (State, error) {
+	if t.status != Dealing || t.active == nil {
+		return t.State(), errors.New("table: no hand in progress")
+	}
+	if a.Type == Straddle {
+		return t.straddle()
+	}
+	if includes(t.LegalActions(), a.Type) == false {
+		return t.State(), errors.New("table: illegal action attempted")
+	}
+	// TODO enforce limits, min bets
+	switch a.Type {
+	case Fold:
+		t.active.Folded = true
+	case Check:
+	case Call:
+		t.active.contribute(t.owed())
+	case Bet, Raise:
+		if a.Chips < t.options.Stakes.BigBlind {
+			return t.State(), errors.New("table: bet or raise must be a minimum of the big blind")
+		}
+		t.active.contribute(t.owed())
+		t.active.contribute(a.Chips)
+	case AllIn:
+		t.active.contribute(t.active.Chips)
+	}
+	if t.active.ChipsInPot > t.cost {
+		// Everyone else has to respond to a bet or raise
+		t.cost = t.active.ChipsInPot
+		t.resetAction()
+	}
+	t.active.Acted = true
+	t.update(t.active.Seat)
+	return t.State(), nil
+}
+
+// straddle raises the big blind by itself on behalf of the active player,
+// who must be the first to act pre-flop, and keeps the action on them once
+// everyone else has responded.
+func (t *Table) straddle() (State, error) {
+	if !t.CanStraddle() {
+		return t.State(), errors.New("table: only the first player to act pre-flop can straddle")
+	}
+	t.active.contribute(t.owed() + t.options.Stakes.BigBlind)
+	t.cost = t.active.ChipsInPot
+	t.resetAction()
+	t.update(t.active.Seat)
+	return t.State(), nil
+}
+
+// CanStraddle reports whether the active player can straddle: it is their
+// first action pre-flop, no one has acted before them, and they have more
+// than the straddle behind.
+func (t *Table) CanStraddle() bool {
+	if t.status != Dealing || t.active == nil || t.round != PreFlop {
+		return false
+	}
+	if t.cost != t.options.Stakes.Ante+t.options.Stakes.BigBlind {
+		return false
+	}
+	for _, seat := range t.seats {
+		// Players sitting out are checked or folded without acting
+		if seat.Acted && !seat.defaulting {
+			return false
+		}
+	}
+	return t.active.Chips > t.owed()+t.options.Stakes.BigBlind
+}
+
+func (t *Table) Seats() []Player {
+	seats := []Player{}
+	for _, seat := range t.seats {
+		seats = append(seats, seat.copy())
+	}
+	return seats
+}
+
+func (t *Table) LegalActions() []ActionType {
+	if t.status != Dealing || t.active == nil {
+		return nil
+	}
+	if t.owed() == 0 {
+		return []ActionType{Fold, Check, Bet, AllIn}
+	}
+	if t.owed() >= t.active.Chips {
+		return []ActionType{Fold, Call, AllIn}
+	}
+	return []ActionType{Fold, Call, Raise, AllIn}
+}
+
+// Active returns the player whose turn it is, or an empty player if no one
+// can act.
+func (t *Table) Active() *Player {
+	if t.active == nil {
+		return &Player{}
+	}
+	p := t.active.copy()
+	return &p
+}
+
+// NewRound deals the next hand once the last one is over, moving the button
+// on, and returns the state of the table after the blinds are posted.
+func (t *Table) NewRound() State {
+	t.result = Result{}
+	t.setupHand()
+	return t.State()
+}
+
+// AddPlayer seats a new player with the table's buy-in. They are dealt in
+// from the next hand unless they are defaulting.
+func (t *Table) AddPlayer(id string, defaulting bool) {
+	if p := t.find(id); p != nil {
+		p.defaulting = defaulting
+		return
+	}
+	t.seats = append(t.seats, &Player{
+		ID:         id,
+		Seat:       len(t.seats),
+		Chips:      t.options.Buyin,
+		SittingOut: true,
+		defaulting: defaulting,
+	})
+}
+
+// SetPlayerDefaulting sets whether a player is sitting out. A defaulting
+// player is not dealt into new hands, and checks or folds whenever the
+// action reaches them in a hand they have already been dealt into.
+func (t *Table) SetPlayerDefaulting(id string, defaulting bool) {
+	if p := t.find(id); p != nil {
+		p.defaulting = defaulting
+	}
+}
+
+// BuyPlayerIn resets a player's stack to the table's buy-in.
+func (t *Table) BuyPlayerIn(id string) error {
+	p := t.find(id)
+	if p == nil {
+		return errors.New("table: player not found")
+	}
+	if t.inHand(p) {
+		return errors.New("table: player is in a hand")
+	}
+	p.Chips = t.options.Buyin
+	return nil
+}
+
+// SetPlayerChips sets the chips a player has behind, not counting any they
+// have already put in the pot.
+func (t *Table) SetPlayerChips(id string, chips int) error {
+	p := t.find(id)
+	if p == nil {
+		return errors.New("table: player not found")
+	}
+	if chips < 0 {
+		return errors.New("table: chips must not be negative")
+	}
+	p.Chips = chips
+	if t.inHand(p) && !p.Folded {
+		p.AllIn = chips == 0
+	}
+	return nil
+}
+
+// RemovePlayer takes a player's seat away. A player can't be removed while
+// they are dealt into a hand.
+func (t *Table) RemovePlayer(id string) error {
+	p := t.find(id)
+	if p == nil {
+		return errors.New("table: player not found")
+	}
+	if t.inHand(p) {
+		return errors.New("table: player is in a hand")
+	}
+	seats := make([]*Player, 0, len(t.seats)-1)
+	for _, seat := range t.seats {
+		if seat != p {
+			seats = append(seats, seat)
+		}
+	}
+	// Keep the button, and the blinds, with the players they were on, or
+	// on the seat before if it was theirs, so that it moves on as normal
+	t.button = shiftSeat(t.button, p.Seat)
+	t.sb = shiftSeat(t.sb, p.Seat)
+	t.bb = shiftSeat(t.bb, p.Seat)
+	t.seats = seats
+	for i, seat := range t.seats {
+		seat.Seat = i
+	}
+	if t.button < 0 {
+		t.button = len(t.seats) - 1
+	}
+	return nil
+}
+
+func shiftSeat(seat int, removed int) int {
+	if seat >= removed {
+		return seat - 1
+	}
+	return seat
+}
+
+// update moves the action on from the given seat, dealing the next street
+// or paying out if the betting round is over.
+func (t *Table) update(from int) {
+	for {
+		if len(t.contesting()) == 1 {
+			t.payout()
+			return
+		}
+		seat := t.nextToAct(from)
+		if seat == -1 {
+			if t.round == River {
+				t.payout()
+				return
+			}
+			t.round++
+			t.setupRound()
+			from = t.button
+			continue
+		}
+		t.active = t.seats[seat]
+		if !t.active.defaulting {
+			return
+		}
+		// Players who are sitting out check or fold when it's their turn
+		if t.owed() > 0 {
+			t.active.Folded = true
+		}
+		t.active.Acted = true
+		from = seat
+	}
+}
+
+// setupHand deals a new hand to everyone with chips who isn't defaulting,
+// and posts antes and blinds.
+func (t *Table) setupHand() {
+	t.round = PreFlop
+	t.cards = nil
+	t.active = nil
+	t.cost = 0
+	dealtIn := 0
+	for _, seat := range t.seats {
+		seat.SittingOut = seat.defaulting || seat.Chips == 0
+		seat.Cards = nil
+		seat.ChipsInPot = 0
+		seat.DeadChips = 0
+		seat.Acted = false
+		seat.Folded = false
+		seat.AllIn = false
+		if !seat.SittingOut {
+			dealtIn++
+		}
+	}
+	if dealtIn < 2 {
+		t.status = Broken
+		return
+	}
+	t.status = Dealing
+	t.button = t.nextSeat(t.button)
+	t.sb = t.nextSeat(t.button)
+	t.bb = t.nextSeat(t.sb)
+	if dealtIn == 2 {
+		t.sb = t.button
+		t.bb = t.nextSeat(t.button)
+	}
+	t.deck = t.dealer.Deck()
+	stakes := t.options.Stakes
+	for _, seat := range t.seats {
+		if !seat.SittingOut {
+			seat.Cards = t.deck.PopMulti(HoleCards(t.options.Variant))
+			if stakes.AnteType == ClassicAnte {
+				seat.contribute(stakes.Ante)
+			}
+		}
+	}
+	t.seats[t.sb].contribute(stakes.SmallBlind)
+	t.seats[t.bb].contribute(stakes.BigBlind)
+	t.cost = stakes.BigBlind
+	switch stakes.AnteType {
+	case ClassicAnte:
+		t.cost += stakes.Ante
+	case BigBlindAnte:
+		t.seats[t.bb].postDead(stakes.Ante * dealtIn)
+	case ButtonAnte:
+		t.seats[t.button].postDead(stakes.Ante * dealtIn)
+	}
+	t.update(t.bb)
+}
+
+// setupRound deals the cards for the current street.
+func (t *Table) setupRound() {
+	t.resetAction()
+	switch t.round {
+	case Flop:
+		t.cards = t.deck.PopMulti(3)
+	case Turn, River:
+		t.cards = append(t.cards, t.deck.Pop())
+	}
+}
+
+func (t *Table) payout() {
+	t.active = nil
+	returned := t.returnUncalled()
+	contesting := t.contesting()
+	hands := map[*Player]*hand.Hand{}
+	if len(contesting) > 1 {
+		for _, seat := range contesting {
+			hands[seat] = BestHand(t.options.Variant, seat.Cards, t.cards)
+		}
+	}
+	pots := []Pot{}
+	won := map[*Player]bool{}
+	for _, pot := range t.pots() {
+		p := Pot{Chips: pot.chips, Shares: map[string]int{}}
+		for _, seat := range pot.contesting {
+			p.Contesting = append(p.Contesting, seat.ID)
+		}
+		// sort by best hand first
+		sort.SliceStable(pot.contesting, func(i, j int) bool {
+			iHand := hands[pot.contesting[i]]
+			jHand := hands[pot.contesting[j]]
+			return iHand != nil && iHand.CompareTo(jHand) > 0
+		})
+		// select winners who split pot if more than one
+		winners := []*Player{}
+		h1 := hands[pot.contesting[0]]
+		for _, seat := range pot.contesting {
+			h2 := hands[seat]
+			if h1 != nil && h1.CompareTo(h2) != 0 {
+				break
+			}
+			winners = append(winners, seat)
+		}
+		// sort closest to the button for spare chips in split pot
+		sort.Slice(winners, func(i, j int) bool {
+			iDist := t.distanceFromButton(winners[i])
+			jDist := t.distanceFromButton(winners[j])
+			return iDist < jDist
+		})
+		// payout chips
+		for i, seat := range winners {
+			share := pot.chips / len(winners)
+			if (pot.chips % len(winners)) > i {
+				share++
+			}
+			seat.Chips += share
+			won[seat] = true
+			p.Winners = append(p.Winners, seat.ID)
+			p.Shares[seat.ID] = share
+		}
+		pots = append(pots, p)
+	}
+	t.result = Result{
+		TableCards: append([]hand.Card(nil), t.cards...),
+		Pots:       pots,
+		Returned:   returned,
+	}
+	for _, seat := range t.seats {
+		if won[seat] {
+			t.result.Winners = append(t.result.Winners, seat.copy())
+		}
+		if seat.inPlay() {
+			t.result.Contestants = append(t.result.Contestants, seat.copy())
+		}
+	}
+	if t.options.OneShot {
+		t.status = Done
+		return
+	}
+	t.setupHand()
+}
+
+// returnUncalled hands back whatever the biggest contributor put in beyond
+// what anyone else did, since no one called it.
+func (t *Table) returnUncalled() map[string]int {
+	var top *Player
+	second := 0
+	for _, seat := range t.seats {
+		if top == nil || seat.ChipsInPot > top.ChipsInPot {
+			if top != nil {
+				second = top.ChipsInPot
+			}
+			top = seat
+		} else if seat.ChipsInPot > second {
+			second = seat.ChipsInPot
+		}
+	}
+	if top == nil || top.ChipsInPot <= second {
+		return nil
+	}
+	excess := top.ChipsInPot - second
+	top.ChipsInPot -= excess
+	top.Chips += excess
+	return map[string]int{top.ID: excess}
+}
+
+type sidePot struct {
+	contesting []*Player
+	chips      int
+}
+
+// pots splits the chips in the pot into a main pot and side pots, each one
+// holding what every player put in between the previous all-in amount and
+// the next.
+func (t *Table) pots() []*sidePot {
+	contesting := t.contesting()
+	sort.SliceStable(contesting, func(i, j int) bool {
+		return contesting[i].ChipsInPot < contesting[j].ChipsInPot
+	})
+	costs := []int{}
+	for _, seat := range contesting {
+		if contains(costs, seat.ChipsInPot) == false {
+			costs = append(costs, seat.ChipsInPot)
+		}
+	}
+	pots := []*sidePot{}
+	for i, cost := range costs {
+		pot := &sidePot{}
+		min := 0
+		if i != 0 {
+			min = costs[i-1]
+		}
+		for _, seat := range t.seats {
+			chips := seat.ChipsInPot
+			if i == len(costs)-1 {
+				// Anything left over goes in the last pot
+				pot.chips += max(chips-min, 0)
+				continue
+			}
+			pot.chips += max(minInt(chips, cost)-min, 0)
+		}
+		for _, seat := range contesting {
+			if seat.ChipsInPot >= cost {
+				pot.contesting = append(pot.contesting, seat)
+			}
+		}
+		// Sort back into seat order so ties are broken the same way
+		// every time
+		sort.Slice(pot.contesting, func(i, j int) bool {
+			return pot.contesting[i].Seat < pot.contesting[j].Seat
+		})
+		if pot.chips > 0 || len(pots) == 0 && i == len(costs)-1 {
+			pots = append(pots, pot)
+		}
+	}
+	// Dead money belongs to everyone still in the hand
+	for _, seat := range t.seats {
+		pots[0].chips += seat.DeadChips
+	}
+	return pots
+}
+
+func (t *Table) resetAction() {
+	for _, seat := range t.seats {
+		seat.Acted = false
+	}
+}
+
+// nextSeat returns the next seat after the given one that has been dealt
+// into the current hand.
+func (t *Table) nextSeat(seat int) int {
+	for i := 0; i < len(t.seats); i++ {
+		seat = (seat + 1) % len(t.seats)
+		if !t.seats[seat].SittingOut {
+			return seat
+		}
+	}
+	return seat
+}
+
+// nextToAct returns the next seat after the given one whose player still
+// has to act in this betting round, or -1 if the round is over.
+func (t *Table) nextToAct(seat int) int {
+	for i := 0; i < len(t.seats); i++ {
+		seat = (seat + 1) % len(t.seats)
+		p := t.seats[seat]
+		if !p.inPlay() || p.AllIn {
+			continue
+		}
+		if p.ChipsInPot < t.cost || (!p.Acted && t.canRespond(p)) {
+			return seat
+		}
+	}
+	return -1
+}
+
+// canRespond reports whether anyone other than the given player could still
+// call a bet.
+func (t *Table) canRespond(p *Player) bool {
+	for _, seat := range t.seats {
+		if seat != p && seat.inPlay() && !seat.AllIn {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Table) owed() int {
+	return t.cost - t.active.ChipsInPot
+}
+
+func (t *Table) distanceFromButton(p *Player) int {
+	seat := t.button
+	dist := 0
+	for i := 0; i < len(t.seats); i++ {
+		seat = (seat + 1) % len(t.seats)
+		dist++
+		if p.Seat == seat {
+			return dist
+		}
+	}
+	return dist
+}
+
+func (t *Table) contesting() []*Player {
+	contesting := []*Player{}
+	for _, seat := range t.seats {
+		if seat.inPlay() {
+			contesting = append(contesting, seat)
+		}
+	}
+	return contesting
+}
+
+// inHand reports whether the player has been dealt into a hand that is
+// still being played.
+func (t *Table) inHand(p *Player) bool {
+	return t.status == Dealing && !p.SittingOut
+}
+
+func (t *Table) find(id string) *Player {
+	for _, seat := range t.seats {
+		if seat.ID == id {
+			return seat
+		}
+	}
+	return nil
+}
+
+func (t *Table) seatAt(seat int) Player {
+	if seat < 0 || seat >= len(t.seats) {
+		return Player{}
+	}
+	return t.seats[seat].copy()
+}
+
+type Player struct {
+	ID         string
+	Seat       int
+	Chips      int
+	ChipsInPot int
+	// DeadChips are what the player put in the pot for everyone, such as a
+	// big-blind ante. They go in the main pot without counting towards the
+	// player's bets.
+	DeadChips int
+	Acted     bool
+	Folded    bool
+	AllIn     bool
+	// SittingOut is set for players who weren't dealt into the current hand
+	SittingOut bool
+	Cards      []hand.Card
+	defaulting bool
+}
+
+func (p *Player) contribute(chips int) {
+	amount := chips
+	if p.Chips <= amount {
+		amount = p.Chips
+		p.AllIn = true
+	}
+	p.ChipsInPot += amount
+	p.Chips -= amount
+}
+
+// postDead puts as much of the given chips as the player has in the pot as
+// dead money.
+func (p *Player) postDead(chips int) {
+	amount := chips
+	if p.Chips <= amount {
+		amount = p.Chips
+		p.AllIn = true
+	}
+	p.DeadChips += amount
+	p.Chips -= amount
+}
+
+// inPlay reports whether the player is in the current hand and hasn't
+// folded.
+func (p *Player) inPlay() bool {
+	return !p.SittingOut && !p.Folded
+}
+
+func (p *Player) copy() Player {
+	c := *p
+	c.Cards = append([]hand.Card(nil), p.Cards...)
+	return c
+}
+
+func includes(actions []ActionType, include ...ActionType) bool {
+	for _, a1 := range include {
+		found := false
+		for _, a2 := range actions {
+			found = found || a1 == a2
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func max(i, j int) int {
+	if i > j {
+		return i
+	}
+	return j
+}
+
+func minInt(i, j int) int {
+	if i < j {
+		return i
+	}
+	return j
+}
+
+func contains(a []int, i int) bool {
+	for _, v := range a {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
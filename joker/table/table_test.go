@@ -0,0 +1,369 @@
+package table_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/jokertest"
+	"github.com/alcamerone/joker/table"
+)
+
+type testCase struct {
+	start       *table.Table
+	actions     []table.Action
+	condition   func(table.State) bool
+	description string
+}
+
+var (
+	testCases = []testCase{
+		{
+			start:   threePerson100Buyin(),
+			actions: nil,
+			condition: func(s table.State) bool {
+				return s.Seats[0].Chips == 98 && s.Seats[1].Chips == 100 && s.Seats[2].Chips == 99 && s.Active.Seat == 1
+			},
+			description: "initial blinds",
+		},
+		{
+			start: threePerson100Buyin(),
+			actions: []table.Action{
+				{table.Raise, 5},
+			},
+			condition: func(s table.State) bool {
+				return s.Seats[0].Chips == 98 && s.Seats[1].Chips == 93 && s.Seats[2].Chips == 99 && s.Active.Seat == 2 && s.Cost == 7
+			},
+			description: "preflop raise",
+		},
+		{
+			start: threePerson100Buyin(),
+			actions: []table.Action{
+				{table.Raise, 5},
+				{table.Call, 0},
+				{table.Fold, 0},
+				{table.Check, 0},
+				{table.Bet, 5},
+				{table.Fold, 0},
+			},
+			condition: func(s table.State) bool {
+				return s.Seats[0].Chips == 97 && s.Seats[1].Chips == 107 && s.Seats[2].Chips == 93 && s.Active.Seat == 2 && s.Button == 2
+			},
+			description: "full hand 1",
+		},
+	}
+)
+
+func TestTable(t *testing.T) {
+	for _, tc := range testCases {
+		tbl := tc.start
+		for _, a := range tc.actions {
+			if _, err := tbl.Act(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tc.condition(tbl.State()) == false {
+			t.Fatalf(tc.description)
+		}
+	}
+}
+
+func threePerson100Buyin() *table.Table {
+	src := rand.NewSource(42)
+	r := rand.New(src)
+	dealer := hand.NewDealer(r)
+	opts := table.Options{
+		Variant: table.TexasHoldem,
+		Limit:   table.NoLimit,
+		Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2},
+		Buyin:   100,
+	}
+	ids := []string{"a", "b", "c"}
+	return table.New(dealer, opts, ids, nil)
+}
+
+func TestSidePots(t *testing.T) {
+	// a is the big blind, b is first to act and c is the small blind
+	tbl := oneShot(
+		"As", "Ah", // a
+		"2c", "7d", // b
+		"Ks", "Kh", // c
+		"3d", "8s", "9c", "Jh", "4s")
+	if err := tbl.SetPlayerChips("a", 18); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.SetPlayerChips("c", 49); err != nil {
+		t.Fatal(err)
+	}
+	act(t, tbl, table.Action{Type: table.AllIn}, table.Action{Type: table.Call}, table.Action{Type: table.Call})
+	s := tbl.State()
+	if s.Status != table.Done || len(s.Cards) != 5 {
+		t.Fatalf("board wasn't run out: status %s, %d cards", s.Status, len(s.Cards))
+	}
+	if chips := stacks(s); !reflect.DeepEqual(chips, []int{60, 50, 60}) {
+		t.Fatalf("stacks = %v; want [60 50 60]", chips)
+	}
+	if !reflect.DeepEqual(s.Result.Returned, map[string]int{"b": 50}) {
+		t.Fatalf("returned = %v; want 50 to b", s.Result.Returned)
+	}
+	want := []table.Pot{
+		{
+			Chips:      60,
+			Contesting: []string{"a", "b", "c"},
+			Winners:    []string{"a"},
+			Shares:     map[string]int{"a": 60},
+		},
+		{
+			Chips:      60,
+			Contesting: []string{"b", "c"},
+			Winners:    []string{"c"},
+			Shares:     map[string]int{"c": 60},
+		},
+	}
+	if !reflect.DeepEqual(s.Result.Pots, want) {
+		t.Fatalf("pots = %+v; want %+v", s.Result.Pots, want)
+	}
+}
+
+func TestBigBlindAnte(t *testing.T) {
+	// a is the big blind, and antes for everyone
+	dealer := jokertest.Dealer(jokertest.Cards(
+		"Ks", "Kh", // a
+		"2c", "7d", // b
+		"As", "Ah", // c
+		"3d", "8s", "9c", "Jh", "4s"))
+	opts := table.Options{
+		Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2, Ante: 1, AnteType: table.BigBlindAnte},
+		Buyin:   100,
+		OneShot: true,
+	}
+	tbl := table.New(dealer, opts, []string{"a", "b", "c"}, nil)
+	s := tbl.State()
+	if s.Seats[0].DeadChips != 3 || s.Seats[0].Chips != 95 || s.Cost != 2 || s.Pot != 6 {
+		t.Fatalf("a = %+v, cost = %d, pot = %d; want 3 dead, 95 behind, cost 2 and pot 6", s.Seats[0], s.Cost, s.Pot)
+	}
+	if err := tbl.SetPlayerChips("b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.SetPlayerChips("c", 4); err != nil {
+		t.Fatal(err)
+	}
+	act(t, tbl, table.Action{Type: table.AllIn}, table.Action{Type: table.AllIn}, table.Action{Type: table.Call})
+	s = tbl.State()
+	// The dead ante goes in the main pot, which c can win despite only
+	// putting in 5
+	want := []table.Pot{
+		{
+			Chips:      18,
+			Contesting: []string{"a", "b", "c"},
+			Winners:    []string{"c"},
+			Shares:     map[string]int{"c": 18},
+		},
+		{
+			Chips:      10,
+			Contesting: []string{"a", "b"},
+			Winners:    []string{"a"},
+			Shares:     map[string]int{"a": 10},
+		},
+	}
+	if !reflect.DeepEqual(s.Result.Pots, want) {
+		t.Fatalf("pots = %+v; want %+v", s.Result.Pots, want)
+	}
+	if chips := stacks(s); !reflect.DeepEqual(chips, []int{97, 0, 18}) {
+		t.Fatalf("stacks = %v; want [97 0 18]", chips)
+	}
+}
+
+func TestOmaha(t *testing.T) {
+	// a would have the nut flush with one heart, but in Omaha b's straight
+	// made with two hole cards wins
+	dealer := jokertest.Dealer(jokertest.Cards(
+		"Ah", "7d", "7c", "5s", // a
+		"Kc", "9d", "4c", "4s", // b
+		"2c", "3c", "5d", "6d", // c
+		"Qh", "Jh", "Th", "3h", "2s"))
+	opts := table.Options{
+		Variant: table.OmahaHi,
+		Limit:   table.PotLimit,
+		Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2},
+		Buyin:   100,
+		OneShot: true,
+	}
+	tbl := table.New(dealer, opts, []string{"a", "b", "c"}, nil)
+	for _, seat := range tbl.Seats() {
+		if len(seat.Cards) != 4 {
+			t.Fatalf("%s was dealt %d cards; want 4", seat.ID, len(seat.Cards))
+		}
+	}
+	act(t, tbl, table.Action{Type: table.Call}, table.Action{Type: table.Call}, table.Action{Type: table.Check})
+	for i := 0; i < 9; i++ {
+		act(t, tbl, table.Action{Type: table.Check})
+	}
+	s := tbl.State()
+	if chips := stacks(s); !reflect.DeepEqual(chips, []int{98, 104, 98}) {
+		t.Fatalf("stacks = %v; want [98 104 98]", chips)
+	}
+}
+
+func TestSplitPot(t *testing.T) {
+	// The board plays, so the pot is split and the odd chip goes to the
+	// first winner after the button
+	tbl := oneShot(
+		"2c", "3d", // a
+		"2d", "3c", // b
+		"2h", "3h", // c
+		"Ts", "Js", "Qs", "Ks", "As")
+	act(t, tbl,
+		table.Action{Type: table.Raise, Chips: 3},
+		table.Action{Type: table.Fold},
+		table.Action{Type: table.Call},
+		table.Action{Type: table.Check},
+		table.Action{Type: table.Check},
+		table.Action{Type: table.Check},
+		table.Action{Type: table.Check},
+		table.Action{Type: table.Check},
+		table.Action{Type: table.Check})
+	s := tbl.State()
+	if s.Status != table.Done {
+		t.Fatalf("status = %s; want Done", s.Status)
+	}
+	// a put in 5, b 5 and c 1
+	if chips := stacks(s); !reflect.DeepEqual(chips, []int{101, 100, 99}) {
+		t.Fatalf("stacks = %v; want [101 100 99]", chips)
+	}
+	if winners := s.Result.Pots[0].Winners; !reflect.DeepEqual(winners, []string{"a", "b"}) {
+		t.Fatalf("winners = %v; want [a b]", winners)
+	}
+}
+
+func TestFoldedBlindReturnsUncalledRaise(t *testing.T) {
+	tbl := oneShot()
+	act(t, tbl,
+		table.Action{Type: table.Raise, Chips: 10},
+		table.Action{Type: table.Fold},
+		table.Action{Type: table.Fold})
+	s := tbl.State()
+	if chips := stacks(s); !reflect.DeepEqual(chips, []int{98, 103, 99}) {
+		t.Fatalf("stacks = %v; want [98 103 99]", chips)
+	}
+	if s.Result.Pots[0].Chips != 5 || s.Result.Returned["b"] != 10 {
+		t.Fatalf("pot = %d, returned = %v; want 5 and 10 to b", s.Result.Pots[0].Chips, s.Result.Returned)
+	}
+	if len(s.Result.Contestants) != 1 || s.Result.Winners[0].ID != "b" {
+		t.Fatalf("result = %+v; want b winning uncontested", s.Result)
+	}
+}
+
+func TestStraddle(t *testing.T) {
+	tbl := oneShot()
+	act(t, tbl, table.Action{Type: table.Straddle})
+	s := tbl.State()
+	if s.Cost != 4 || s.Seats[1].Chips != 96 || s.Active.ID != "c" {
+		t.Fatalf("cost = %d, b has %d, active = %s; want 4, 96 and c", s.Cost, s.Seats[1].Chips, s.Active.ID)
+	}
+	if _, err := tbl.Act(table.Action{Type: table.Straddle}); err == nil {
+		t.Fatal("straddled after the first player to act")
+	}
+	act(t, tbl, table.Action{Type: table.Call}, table.Action{Type: table.Call})
+	// Everyone called, but the straddler still has the option
+	s = tbl.State()
+	if s.Round != table.PreFlop || s.Active.ID != "b" {
+		t.Fatalf("round = %s, active = %s; want b to act pre-flop", s.Round, s.Active.ID)
+	}
+	act(t, tbl, table.Action{Type: table.Check})
+	if s = tbl.State(); s.Round != table.Flop {
+		t.Fatalf("round = %s; want flop once the straddler checks", s.Round)
+	}
+}
+
+func TestNewRound(t *testing.T) {
+	tbl := oneShot()
+	act(t, tbl, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	if _, err := tbl.Act(table.Action{Type: table.Check}); err == nil {
+		t.Fatal("acted after the hand was over")
+	}
+	tbl.AddPlayer("d", false)
+	if err := tbl.RemovePlayer("b"); err != nil {
+		t.Fatal(err)
+	}
+	s := tbl.NewRound()
+	if s.Status != table.Dealing || s.Result.Pots != nil {
+		t.Fatalf("status = %s, result = %+v; want a fresh hand", s.Status, s.Result)
+	}
+	// The button moves on from b to c, who was after them
+	if s.Dealer.ID != "c" || s.SmallBlind.ID != "d" || s.BigBlind.ID != "a" {
+		t.Fatalf(
+			"button, blinds = %s, %s, %s; want c, d, a",
+			s.Dealer.ID,
+			s.SmallBlind.ID,
+			s.BigBlind.ID)
+	}
+	if len(s.Seats[2].Cards) != 2 || s.Seats[2].SittingOut {
+		t.Fatalf("d wasn't dealt in: %+v", s.Seats[2])
+	}
+}
+
+func TestSittingOut(t *testing.T) {
+	src := rand.NewSource(42)
+	opts := table.Options{
+		Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2},
+		Buyin:   100,
+		OneShot: true,
+	}
+	tbl := table.New(hand.NewDealer(rand.New(src)), opts, []string{"a", "b", "c"}, []string{"b"})
+	s := tbl.State()
+	if !s.Seats[1].SittingOut || s.Seats[1].Cards != nil {
+		t.Fatalf("b was dealt in: %+v", s.Seats[1])
+	}
+	if err := tbl.RemovePlayer("a"); err == nil {
+		t.Fatal("removed a player during a hand")
+	}
+	// c is on the button and small blind heads up, and acts first
+	if s.Dealer.ID != "c" || s.BigBlind.ID != "a" || s.Active.ID != "c" {
+		t.Fatalf("button = %s, big blind = %s, active = %s", s.Dealer.ID, s.BigBlind.ID, s.Active.ID)
+	}
+	// Players who sit out during a hand check or fold when it's their turn
+	tbl.SetPlayerDefaulting("a", true)
+	s, err := tbl.Act(table.Action{Type: table.Raise, Chips: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != table.Done || s.Result.Winners[0].ID != "c" {
+		t.Fatalf("status = %s, result = %+v; want a to have folded", s.Status, s.Result)
+	}
+}
+
+func oneShot(cards ...string) *table.Table {
+	var dealer hand.Dealer
+	if len(cards) > 0 {
+		dealer = jokertest.Dealer(jokertest.Cards(cards...))
+	} else {
+		dealer = hand.NewDealer(rand.New(rand.NewSource(42)))
+	}
+	opts := table.Options{
+		Variant: table.TexasHoldem,
+		Limit:   table.NoLimit,
+		Stakes:  table.Stakes{SmallBlind: 1, BigBlind: 2},
+		Buyin:   100,
+		OneShot: true,
+	}
+	return table.New(dealer, opts, []string{"a", "b", "c"}, nil)
+}
+
+func act(t *testing.T, tbl *table.Table, actions ...table.Action) {
+	t.Helper()
+	for _, a := range actions {
+		if _, err := tbl.Act(a); err != nil {
+			t.Fatalf("%s by %s: %s", a.Type, tbl.Active().ID, err.Error())
+		}
+	}
+}
+
+func stacks(s table.State) []int {
+	chips := []int{}
+	for _, seat := range s.Seats {
+		chips = append(chips, seat.Chips)
+	}
+	return chips
+}
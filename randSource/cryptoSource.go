@@ -0,0 +1,99 @@
+/*    package "randSource" provides random number sources for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package randSource
+
+import (
+	cryptoRand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mathRand "math/rand/v2"
+	"sync"
+)
+
+const SEED_SIZE = 32
+
+// CryptoSource is a rand.Source64 producing a ChaCha8 stream keyed with a
+// 256-bit seed. Seeds should be read from crypto/rand with NewSeed, which
+// makes the output unpredictable to anyone who doesn't know the seed, while
+// still allowing a shuffle to be reproduced once the seed is revealed.
+type CryptoSource struct {
+	r    *mathRand.ChaCha8
+	seed []byte
+	m    sync.Mutex
+}
+
+// NewSeed reads a new seed from crypto/rand.
+func NewSeed() ([]byte, error) {
+	seed := make([]byte, SEED_SIZE)
+	_, err := cryptoRand.Read(seed)
+	if err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// Commitment returns the hex-encoded SHA-256 hash of a seed, which can be
+// published before the seed is used so that it can be verified once revealed.
+func Commitment(seed []byte) string {
+	sum := sha256.Sum256(seed)
+	return hex.EncodeToString(sum[:])
+}
+
+func NewCryptoSource(seed []byte) (*CryptoSource, error) {
+	s := &CryptoSource{}
+	err := s.SetSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetSeed rekeys the source with the given seed, which must be SEED_SIZE
+// bytes long.
+func (s *CryptoSource) SetSeed(seed []byte) error {
+	if len(seed) != SEED_SIZE {
+		return fmt.Errorf("seed must be %d bytes, got %d", SEED_SIZE, len(seed))
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.seed = append([]byte(nil), seed...)
+	s.r = mathRand.NewChaCha8([SEED_SIZE]byte(seed))
+	return nil
+}
+
+func (s *CryptoSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+func (s *CryptoSource) Uint64() uint64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.r.Uint64()
+}
+
+// Seed implements rand.Source. An int64 doesn't hold enough entropy to key
+// the source securely, so this should only be used for testing.
+func (s *CryptoSource) Seed(seed int64) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(seed))
+	sum := sha256.Sum256(buf)
+	s.SetSeed(sum[:])
+}
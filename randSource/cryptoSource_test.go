@@ -0,0 +1,98 @@
+/*    package "randSource" provides random number sources for the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package randSource
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/hand"
+)
+
+func draw(s *CryptoSource, n int) []uint64 {
+	out := make([]uint64, n)
+	for i := range out {
+		out[i] = s.Uint64()
+	}
+	return out
+}
+
+func TestCryptoSource(t *testing.T) {
+	seed := bytes.Repeat([]byte{1}, SEED_SIZE)
+	other := bytes.Repeat([]byte{2}, SEED_SIZE)
+	tests := []struct {
+		name  string
+		first []byte
+		next  []byte
+		same  bool
+	}{
+		{name: "same seed", first: seed, next: seed, same: true},
+		{name: "different seed", first: seed, next: other, same: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewCryptoSource(tt.first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := draw(s, 8)
+			// Reseeding starts the stream again, as before every hand
+			if err = s.SetSeed(tt.next); err != nil {
+				t.Fatal(err)
+			}
+			if got := draw(s, 8); reflect.DeepEqual(got, want) != tt.same {
+				t.Errorf("reseeded stream = %v; first stream = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCryptoSourceShuffle(t *testing.T) {
+	seed, err := NewSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deal := func() []hand.Card {
+		s, err := NewCryptoSource(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hand.NewDealer(rand.New(s)).Deck().PopMulti(52)
+	}
+	if first, second := deal(), deal(); !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed dealt %v and then %v", first, second)
+	}
+}
+
+func TestSetSeedLength(t *testing.T) {
+	for _, size := range []int{0, SEED_SIZE - 1, SEED_SIZE + 1} {
+		if _, err := NewCryptoSource(make([]byte, size)); err == nil {
+			t.Errorf("accepted a %d byte seed", size)
+		}
+	}
+}
+
+func TestCommitment(t *testing.T) {
+	want := "66687aadf862bd776c8fc18b8e9f8e20089714856ee233b3902a591d0d5f2925"
+	if got := Commitment(make([]byte, SEED_SIZE)); got != want {
+		t.Errorf("Commitment(zero seed) = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+/*    package "replay" replays hands played on the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package replay
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/randSource"
+)
+
+type EventType int
+
+const (
+	EventUnknown EventType = iota
+	EventNewRound
+	EventAction
+	EventAddPlayer
+	EventSetDefaulting
+	EventBuyIn
+	EventSetChips
+	EventRemovePlayer
+)
+
+// Event is a single change made to a table. Only the fields relevant to the
+// event's type are set.
+type Event struct {
+	Type       EventType
+	PlayerId   string       `json:",omitempty"`
+	Action     table.Action `json:",omitempty"`
+	Seed       []byte       `json:",omitempty"`
+	Defaulting bool         `json:",omitempty"`
+	Chips      int          `json:",omitempty"`
+}
+
+// Log holds everything needed to rebuild a table from scratch: the options
+// and seats it was created with, the seed used to shuffle the first hand and
+// every event applied to it since, in order.
+type Log struct {
+	Options    table.Options
+	PlayerIds  []string
+	SittingOut []string
+	Seed       []byte
+	Events     []Event
+}
+
+// Recorder builds up the log for a live table.
+type Recorder struct {
+	sync.Mutex
+	log Log
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start discards any previous log and begins a new one for a freshly created
+// table.
+func (r *Recorder) Start(
+	opts table.Options,
+	playerIds []string,
+	sittingOut []string,
+	seed []byte,
+) {
+	r.Lock()
+	defer r.Unlock()
+	r.log = Log{
+		Options:    opts,
+		PlayerIds:  append([]string(nil), playerIds...),
+		SittingOut: append([]string(nil), sittingOut...),
+		Seed:       seed,
+		Events:     make([]Event, 0),
+	}
+}
+
+func (r *Recorder) Record(e Event) {
+	r.Lock()
+	defer r.Unlock()
+	r.log.Events = append(r.log.Events, e)
+}
+
+func (r *Recorder) Log() Log {
+	r.Lock()
+	defer r.Unlock()
+	l := r.log
+	l.Events = append([]Event(nil), r.log.Events...)
+	return l
+}
+
+// Replay rebuilds the table described by the log, returning the table state
+// after it was created followed by the state after each event.
+func Replay(l Log) ([]table.State, error) {
+	src, err := randSource.NewCryptoSource(l.Seed)
+	if err != nil {
+		return nil, err
+	}
+	t := table.New(
+		hand.NewDealer(rand.New(src)),
+		l.Options,
+		l.PlayerIds,
+		l.SittingOut)
+	states := make([]table.State, 0, len(l.Events)+1)
+	states = append(states, t.State())
+	for i, e := range l.Events {
+		switch e.Type {
+		case EventNewRound:
+			err = src.SetSeed(e.Seed)
+			if err != nil {
+				return states, fmt.Errorf("event %d: %s", i, err.Error())
+			}
+			states = append(states, t.NewRound())
+			continue
+		case EventAction:
+			if t.Active().ID != e.PlayerId {
+				return states, fmt.Errorf(
+					"event %d: %s acted out of turn; expected %s",
+					i,
+					e.PlayerId,
+					t.Active().ID)
+			}
+			_, err = t.Act(e.Action)
+		case EventAddPlayer:
+			t.AddPlayer(e.PlayerId, e.Defaulting)
+		case EventSetDefaulting:
+			t.SetPlayerDefaulting(e.PlayerId, e.Defaulting)
+		case EventBuyIn:
+			err = t.BuyPlayerIn(e.PlayerId)
+		case EventSetChips:
+			err = t.SetPlayerChips(e.PlayerId, e.Chips)
+		case EventRemovePlayer:
+			err = t.RemovePlayer(e.PlayerId)
+		default:
+			err = fmt.Errorf("unknown event type %d", e.Type)
+		}
+		if err != nil {
+			return states, fmt.Errorf("event %d: %s", i, err.Error())
+		}
+		states = append(states, t.State())
+	}
+	return states, nil
+}
@@ -23,23 +23,37 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alcamerone/joker/hand"
 	"github.com/alcamerone/joker/table"
 	"github.com/alcamerone/pocket2s/types"
 	"github.com/gorilla/websocket"
 )
 
-const SIT_OUT = "SIT OUT"
+const (
+	SIT_OUT  = "SIT OUT"
+	ADD_ON   = "ADD ON"
+	TOP_UP   = "TOP UP"
+	LEAVE    = "LEAVE"
+	SEAT     = "SEAT"
+	STRADDLE = "STRADDLE"
+	SHOW     = "SHOW"
+)
 
 var (
 	inputReader *bufio.Reader
 	conn        *websocket.Conn
 	roomId      string
 	playerId    string
+	token       string
+	password    string
+	seat        string
+	currentGame *types.Game
 )
 
 const (
@@ -74,6 +88,7 @@ func main() {
 		fmt.Println("Sorry, we can't use that name. Try another one:")
 	}
 	fmt.Println("Great! Now which room would you like to join?")
+	fmt.Println("(To enter a multi-table tournament, type tournament/ followed by its name.)")
 	for {
 		// TODO sanitise
 		roomId, err = getInput(false)
@@ -83,6 +98,21 @@ func main() {
 		log.Printf("error scanning: %s", err.Error()) //TODO remove
 		fmt.Println("Sorry, we can't use that room. Try another one:")
 	}
+	fmt.Println("If the room has a password, enter it. Otherwise just hit Enter:")
+	password, err = getInput(false)
+	if err != nil {
+		log.Printf("error scanning: %s", err.Error()) //TODO remove
+	}
+	fmt.Println("If you'd like a particular seat, enter its number. Otherwise just hit Enter:")
+	seat, err = getInput(false)
+	if err != nil {
+		log.Printf("error scanning: %s", err.Error()) //TODO remove
+	}
+	fmt.Println("If you're rejoining a game, enter your session token. Otherwise just hit Enter:")
+	token, err = getInput(false)
+	if err != nil {
+		log.Printf("error scanning: %s", err.Error()) //TODO remove
+	}
 
 	wsDialler := websocket.Dialer{
 		ReadBufferSize:   1024,
@@ -90,7 +120,25 @@ func main() {
 		HandshakeTimeout: 30 * time.Second,
 	}
 
-	conn, _, err = wsDialler.Dial("ws://localhost:2222/connect/"+roomId+"/"+playerId, nil)
+	query := url.Values{}
+	if password != "" {
+		query.Set("password", password)
+	}
+	if token != "" {
+		query.Set("token", token)
+	}
+	if seat != "" {
+		query.Set("seat", seat)
+	}
+	connectUrl := "ws://localhost:2222/connect/" + roomId + "/" + playerId
+	if strings.HasPrefix(roomId, "tournament/") {
+		connectUrl = "ws://localhost:2222/tournament/connect/" +
+			strings.TrimPrefix(roomId, "tournament/") + "/" + playerId
+	}
+	if len(query) > 0 {
+		connectUrl += "?" + query.Encode()
+	}
+	conn, _, err = wsDialler.Dial(connectUrl, nil)
 	if err != nil {
 		log.Fatalf("error establishing connection with server: %s", err.Error())
 	}
@@ -115,30 +163,81 @@ func mainLoop() error {
 		switch msg.Type {
 		case types.MessageTypeHello:
 			fmt.Println("Connection established to Pocket2s server!")
+			if msg.SessionToken != "" {
+				fmt.Printf("Your session token is %s. You'll need it if you have to rejoin.\n", msg.SessionToken)
+			}
+			if strings.HasPrefix(roomId, "tournament/") && msg.RoomId == "" {
+				fmt.Println("You're entered! You'll be given a table when the tournament starts.")
+				continue
+			}
 			fmt.Println("The game will start when there are two or more players and everyone has marked themselves ready.")
 			fmt.Println("Hit Enter when you're ready to start, or type SIT OUT to sit the first round out.")
-			awaitPlayerReady(conn, false)
+			fmt.Println("To buy in for something other than the room's default, type the amount instead.")
+			awaitPlayerReady(conn, false, nil)
 			fmt.Println("Okay! Waiting for other players...")
+		case types.MessageTypeShuffleCommitment:
+			fmt.Printf("The next hand's shuffle seed has hash %s\n", msg.ShuffleCommitment)
 		case types.MessageTypeTableState, types.MessageTypeIllegalAction:
 			if msg.Result != "" {
-				fmt.Println(msg.Result)
+				for _, shown := range msg.Showdown {
+					if shown.Mucked {
+						fmt.Printf("%s mucks.\n", shown.PlayerId)
+					} else {
+						fmt.Printf("%s shows %v.\n", shown.PlayerId, shown.Cards)
+					}
+				}
+				if len(msg.Pots) > 0 {
+					printPots(msg.Pots, msg.Boards)
+					for playerId, chips := range msg.Returned {
+						fmt.Printf("Uncalled bet of %d returned to %s.\n", chips, playerId)
+					}
+				} else {
+					fmt.Println(msg.Result)
+				}
+				if msg.ShuffleSeed != "" {
+					fmt.Printf("That hand was shuffled with seed %s\n", msg.ShuffleSeed)
+				}
+				if msg.AddOn > 0 && msg.PlayerState.Chips > 0 {
+					fmt.Printf("Rebuys are now closed. Type ADD ON to buy an extra %d chips before the next hand.\n", msg.AddOn)
+				}
 				if msg.PlayerState.Chips < 1 {
-					fmt.Println("You're broke! Press Enter to buy back in, or type SIT OUT to observe the rest of the game.")
+					fmt.Println("You're broke! Press Enter (or type an amount) to buy back in, or type SIT OUT to observe the rest of the game.")
 				} else {
 					fmt.Println("Press Enter when you're ready for the next round, or type SIT OUT to sit out the next round.")
+					fmt.Println("To add chips first, type TOP UP followed by the amount. To cash out, type LEAVE.")
+					fmt.Println("To ask for another seat, type SEAT followed by its number.")
+					fmt.Println("To straddle if you're under the gun next hand, type STRADDLE.")
+				}
+				if len(msg.PlayerState.Cards) > 0 {
+					fmt.Println("To show your cards, type SHOW, or SHOW followed by the numbers of the cards to show:")
+					fmt.Println(numberedCards(msg.PlayerState.Cards))
 				}
-				awaitPlayerReady(conn, msg.PlayerState.Chips < 1)
+				awaitPlayerReady(conn, msg.PlayerState.Chips < 1, msg.PlayerState.Cards)
 				fmt.Println("Okay! Waiting for other players...")
 				continue
 			}
 			if msg.Type == types.MessageTypeIllegalAction {
 				fmt.Println("Sorry, that action's not allowed.")
 			}
+			if msg.Game != nil && (currentGame == nil || *currentGame != *msg.Game) {
+				fmt.Printf("Now playing %s.\n", msg.Game.String())
+				currentGame = msg.Game
+			}
 			fmt.Printf(
 				"Dealer: %s\nSmall Blind: %s\nBig Blind: %s\n",
 				msg.TableState.Dealer.ID,
 				msg.TableState.SmallBlind.ID,
 				msg.TableState.BigBlind.ID)
+			if msg.BombPot {
+				fmt.Println("This hand is a bomb pot!")
+			}
+			if msg.TableState.Round == table.PreFlop {
+				for _, seat := range msg.TableState.Seats {
+					if msg.Antes[seat.ID] > 0 {
+						fmt.Printf("%s posted an ante of %d\n", seat.ID, msg.Antes[seat.ID])
+					}
+				}
+			}
 			fmt.Printf("Cards: %v, Pot: %d\n", msg.TableState.Cards, msg.TableState.Pot)
 			if !msg.PlayerState.SittingOut {
 				fmt.Printf(
@@ -150,7 +249,15 @@ func mainLoop() error {
 			if msg.TableState.Active.ID != playerId {
 				fmt.Printf("It is %s's turn...\n", msg.TableState.Active.ID)
 			} else {
-				action := parseTableAction(msg.TableState)
+				if !msg.ActionDeadline.IsZero() {
+					fmt.Printf(
+						"You have %d seconds to act.\n",
+						int(time.Until(msg.ActionDeadline).Seconds()))
+				}
+				if msg.TimeBank > 0 {
+					fmt.Printf("You have %d seconds in your time bank.\n", msg.TimeBank)
+				}
+				action := parseTableAction(msg.TableState, msg.RaiseLimits)
 				err := conn.WriteJSON(
 					types.FromPlayerMessage{
 						Type:   types.MessageTypePlayerAction,
@@ -162,8 +269,69 @@ func mainLoop() error {
 					log.Printf("error sending player action to server: %s", err.Error())
 				}
 			}
+		case types.MessageTypeTimeBank:
+			fmt.Printf(
+				"%s is using their time bank, and has %d seconds to act.\n",
+				msg.PlayerId,
+				int(time.Until(msg.ActionDeadline).Seconds()))
 		case types.MessageTypePlayerAction:
 			fmt.Println(stringifyPlayerAction(msg.PlayerAction))
+		case types.MessageTypeLevelUp:
+			if msg.BlindLevel != nil {
+				fmt.Printf(
+					"Blinds are now level %d: %d/%d, ante %d.\n",
+					msg.BlindLevel.Level,
+					msg.BlindLevel.SmallBlind,
+					msg.BlindLevel.BigBlind,
+					msg.BlindLevel.Ante)
+			}
+		case types.MessageTypePlayerEliminated:
+			for _, standing := range msg.Standings {
+				fmt.Printf(
+					"%s has been eliminated in place %d%s.\n",
+					standing.PlayerId,
+					standing.Place,
+					payoutStr(standing.Payout))
+			}
+		case types.MessageTypeTournamentOver:
+			fmt.Println("The tournament is over! Final standings:")
+			for _, standing := range msg.Standings {
+				fmt.Printf("%d. %s%s\n", standing.Place, standing.PlayerId, payoutStr(standing.Payout))
+			}
+		case types.MessageTypeTableChange:
+			fmt.Printf("You've been seated at table %s.\n", msg.RoomId)
+			fmt.Println("Hit Enter when you're ready to play, or type SIT OUT to sit the next round out.")
+			awaitPlayerReady(conn, false, nil)
+			fmt.Println("Okay! Waiting for other players...")
+		case types.MessageTypeRunItTwice:
+			fmt.Printf("Everyone's all in! Type YES to run it %d times, or anything else to run it once.\n", msg.RunItTimes)
+			answer, inputErr := getInput(true)
+			if inputErr != nil {
+				log.Printf("error reading user input: %s", inputErr.Error()) // TODO remove
+			}
+			err = conn.WriteJSON(types.FromPlayerMessage{
+				Type:  types.MessageTypeRunItTwice,
+				Agree: answer == "YES",
+			})
+			if err != nil {
+				log.Printf("error sending run it twice answer: %s", err.Error()) // TODO remove
+			}
+		case types.MessageTypeShowCards:
+			fmt.Printf("%s shows %v.\n", msg.PlayerId, msg.Cards)
+		case types.MessageTypeSeatChange:
+			fmt.Printf("%s has moved to seat %d.\n", msg.PlayerId, msg.Seat)
+		case types.MessageTypeLeave:
+			if msg.PlayerId != playerId {
+				fmt.Printf("%s has left the table.\n", msg.PlayerId)
+				continue
+			}
+			fmt.Printf("You left the table with %d chips. Thanks for playing!\n", msg.CashOut)
+			fmt.Println("Press Enter (or type an amount) to buy in and sit back down.")
+			awaitPlayerReady(conn, false, nil)
+		case types.MessageTypeError:
+			if msg.Error != nil {
+				fmt.Printf("Error: %s\n", msg.Error.Message)
+			}
 		case types.MessageTypePlayerConnected:
 			fmt.Printf("Player %s has entered the game!\n", msg.PlayerId)
 		case types.MessageTypePlayerDisconnected:
@@ -174,7 +342,70 @@ func mainLoop() error {
 	}
 }
 
-func awaitPlayerReady(conn *websocket.Conn, playerIsBroke bool) {
+// printPots prints who won what from each pot, board by board if the board
+// was run more than once.
+func printPots(pots []types.PotResult, boards [][]hand.Card) {
+	if pots[0].Hand == "" {
+		fmt.Printf("%s wins the pot of %d.\n", pots[0].Winners[0], pots[0].Amount)
+		return
+	}
+	potNum := 0
+	for i, pot := range pots {
+		if i > 0 && pot.Board != pots[i-1].Board {
+			potNum = 0
+		}
+		if len(boards) > 1 && potNum == 0 {
+			fmt.Printf("Board %d: %v\n", pot.Board+1, boards[pot.Board])
+		}
+		name := "Main pot"
+		if potNum > 0 {
+			name = fmt.Sprintf("Side pot %d", potNum)
+		}
+		potNum++
+		winnings := make([]string, len(pot.Winners))
+		for j, winner := range pot.Winners {
+			winnings[j] = fmt.Sprintf("%s wins %d", winner, pot.Awarded[winner])
+		}
+		fmt.Printf(
+			"%s of %d between %s: %s with %s.\n",
+			name,
+			pot.Amount,
+			strings.Join(pot.Eligible, ", "),
+			strings.Join(winnings, ", "),
+			pot.Hand)
+	}
+}
+
+// cardsToShow picks out the cards numbered in the input, counting from 1.
+// No numbers picks out every card.
+func cardsToShow(input string, cards []hand.Card) ([]hand.Card, bool) {
+	show := make([]hand.Card, 0, len(cards))
+	for _, field := range strings.Fields(input) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(cards) {
+			return nil, false
+		}
+		show = append(show, cards[n-1])
+	}
+	return show, true
+}
+
+func numberedCards(cards []hand.Card) string {
+	numbered := make([]string, len(cards))
+	for i, card := range cards {
+		numbered[i] = fmt.Sprintf("%d: %s", i+1, card)
+	}
+	return strings.Join(numbered, "  ")
+}
+
+func payoutStr(payout int) string {
+	if payout == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", winning %d", payout)
+}
+
+func awaitPlayerReady(conn *websocket.Conn, playerIsBroke bool, cards []hand.Card) {
 	var (
 		input string
 		err   error
@@ -182,13 +413,82 @@ func awaitPlayerReady(conn *websocket.Conn, playerIsBroke bool) {
 	for {
 		input, err = getInput(true)
 		if err == nil {
-			if input == SIT_OUT {
+			if input == ADD_ON {
+				err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeAddOn})
+				if err != nil {
+					log.Printf("error sending add-on message: %s", err.Error()) // TODO remove
+				}
+				fmt.Println("Now press Enter when you're ready to play!")
+				continue
+			} else if strings.HasPrefix(input, TOP_UP) {
+				amount, convErr := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(input, TOP_UP)))
+				if convErr != nil {
+					fmt.Println("Type TOP UP followed by the number of chips to add.")
+					continue
+				}
+				err = conn.WriteJSON(types.FromPlayerMessage{
+					Type:   types.MessageTypeTopUp,
+					Amount: amount,
+				})
+				if err != nil {
+					log.Printf("error sending top-up message: %s", err.Error()) // TODO remove
+				}
+				fmt.Println("Now press Enter when you're ready to play!")
+				continue
+			} else if strings.HasPrefix(input, SHOW) {
+				show, ok := cardsToShow(strings.TrimPrefix(input, SHOW), cards)
+				if !ok {
+					fmt.Printf("Type SHOW followed by card numbers from 1 to %d.\n", len(cards))
+					continue
+				}
+				err = conn.WriteJSON(types.FromPlayerMessage{
+					Type:  types.MessageTypeShowCards,
+					Cards: show,
+				})
+				if err != nil {
+					log.Printf("error sending show cards message: %s", err.Error()) // TODO remove
+				}
+				fmt.Println("Now press Enter when you're ready to play!")
+				continue
+			} else if input == STRADDLE {
+				err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeStraddle})
+				if err != nil {
+					log.Printf("error sending straddle message: %s", err.Error()) // TODO remove
+				}
+				fmt.Println("Now press Enter when you're ready to play!")
+				continue
+			} else if strings.HasPrefix(input, SEAT) {
+				seatNum, convErr := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(input, SEAT)))
+				if convErr != nil {
+					fmt.Println("Type SEAT followed by the number of the seat you'd like.")
+					continue
+				}
+				err = conn.WriteJSON(types.FromPlayerMessage{
+					Type: types.MessageTypeSeatChange,
+					Seat: seatNum,
+				})
+				if err != nil {
+					log.Printf("error sending seat change message: %s", err.Error()) // TODO remove
+				}
+				fmt.Println("You'll be moved once the seat is free. Now press Enter when you're ready to play!")
+				continue
+			} else if input == LEAVE {
+				err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeLeave})
+			} else if input == SIT_OUT {
 				err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeSitOut})
 			} else {
+				// Anything but a number buys in for the room's default amount
+				amount, _ := strconv.Atoi(input)
 				if playerIsBroke {
-					err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeBuyIn})
+					err = conn.WriteJSON(types.FromPlayerMessage{
+						Type:   types.MessageTypeBuyIn,
+						Amount: amount,
+					})
 				} else {
-					err = conn.WriteJSON(types.FromPlayerMessage{Type: types.MessageTypeReady})
+					err = conn.WriteJSON(types.FromPlayerMessage{
+						Type:   types.MessageTypeReady,
+						Amount: amount,
+					})
 				}
 			}
 			if err != nil {
@@ -205,7 +505,7 @@ func awaitPlayerReady(conn *websocket.Conn, playerIsBroke bool) {
 	}
 }
 
-func parseTableAction(tableState table.State) table.Action {
+func parseTableAction(tableState table.State, limits *types.RaiseLimits) table.Action {
 	var (
 		input string
 		err   error
@@ -215,7 +515,7 @@ func parseTableAction(tableState table.State) table.Action {
 	for {
 		fmt.Printf(
 			"It is your turn. What would you like to do? (Valid actions are %v)\n",
-			validActions(tableState))
+			validActions(tableState, limits))
 		input, err = getInput(true)
 		if err != nil {
 			log.Printf("error scanning input: %s", err.Error()) //TODO remove
@@ -231,14 +531,14 @@ func parseTableAction(tableState table.State) table.Action {
 		case ActionCall:
 			return table.Action{Type: table.Call}
 		case ActionBet:
-			bet, err = parseBet(args)
+			bet, err = parseBet(args, limits)
 			if err != nil {
 				fmt.Printf("Sorry, %s.\n", err.Error())
 				continue
 			}
 			return table.Action{Type: table.Bet, Chips: bet}
 		case ActionRaise:
-			bet, err = parseBet(args)
+			bet, err = parseBet(args, limits)
 			if err != nil {
 				fmt.Printf("Sorry, %s.\n", err.Error())
 				continue
@@ -252,18 +552,42 @@ func parseTableAction(tableState table.State) table.Action {
 	}
 }
 
-func validActions(tableState table.State) []string {
+func validActions(tableState table.State, limits *types.RaiseLimits) []string {
+	canRaise := limits == nil || limits.Max > 0
+	if limits != nil && limits.Max > 0 {
+		if limits.Min == limits.Max {
+			fmt.Printf("You can bet or raise by %d.\n", limits.Max)
+		} else {
+			fmt.Printf("You can bet or raise by %d to %d.\n", limits.Min, limits.Max)
+		}
+	}
+	canGoAllIn := limits == nil || limits.AllIn
+	actions := []string{ActionFold}
 	if tableState.Owed == 0 {
-		return []string{ActionFold, ActionCheck, ActionBet, ActionAllIn}
+		actions = append(actions, ActionCheck)
+		if canRaise {
+			actions = append(actions, ActionBet)
+		}
+		if canGoAllIn {
+			actions = append(actions, ActionAllIn)
+		}
+		return actions
 	}
 	if tableState.Owed > tableState.Active.Chips {
 		return []string{ActionFold, ActionCall}
 	}
 	fmt.Printf("Call cost is %d.\n", tableState.Owed)
-	return []string{ActionFold, ActionCall, ActionRaise, ActionAllIn}
+	actions = append(actions, ActionCall)
+	if canRaise {
+		actions = append(actions, ActionRaise)
+	}
+	if canGoAllIn {
+		actions = append(actions, ActionAllIn)
+	}
+	return actions
 }
 
-func parseBet(args []string) (int, error) {
+func parseBet(args []string, limits *types.RaiseLimits) (int, error) {
 	if len(args) < 2 {
 		return 0, errors.New("you need to tell me the amount you'd like to bet")
 	}
@@ -274,6 +598,14 @@ func parseBet(args []string) (int, error) {
 	if amt < 1 {
 		return 0, errors.New("your bet has to be a number greater than 0")
 	}
+	if limits != nil {
+		if limits.Max == 0 {
+			return 0, errors.New("you can't raise any more this round")
+		}
+		if int(amt) < limits.Min || int(amt) > limits.Max {
+			return 0, fmt.Errorf("your bet has to be between %d and %d", limits.Min, limits.Max)
+		}
+	}
 	return int(amt), nil // TODO fix unsafe conversion
 }
 
@@ -291,6 +623,8 @@ func stringifyPlayerAction(action types.PlayerAction) string {
 		return fmt.Sprintf("%s raises %d.", action.PlayerId, action.Chips)
 	case table.AllIn:
 		return fmt.Sprintf("%s is all in!", action.PlayerId)
+	case table.Straddle:
+		return fmt.Sprintf("%s straddles.", action.PlayerId)
 	default:
 	}
 	log.Printf("unrecognised message type %d", action.Type) //TODO remove
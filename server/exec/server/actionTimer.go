@@ -0,0 +1,189 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+type actionTimer struct {
+	sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	// seq is bumped every time the timer is stopped or restarted, so a
+	// timeout that fires after its turn has already ended can be ignored
+	seq int
+	// playerId is the player the timer is running for, and bankStart is the
+	// time at which they started drawing on their time bank, if they have
+	playerId  string
+	bankStart time.Time
+}
+
+func (t *actionTimer) getDeadline() time.Time {
+	t.Lock()
+	defer t.Unlock()
+	return t.deadline
+}
+
+// stop must be called with the lock held
+func (t *actionTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.deadline = time.Time{}
+	t.playerId = ""
+	t.bankStart = time.Time{}
+	t.seq++
+}
+
+// startActionTimer starts the countdown for the active player in the given
+// state, cancelling any previous countdown, and returns the deadline by which
+// they must act. The zero time is returned if no countdown is running.
+func (r *room) startActionTimer(state table.State) time.Time {
+	r.actionTimer.Lock()
+	defer r.actionTimer.Unlock()
+	r.drainTimeBank()
+	r.actionTimer.stop()
+	if r.opts.ActionTimeout <= 0 ||
+		state.Status == table.Done ||
+		state.Active.ID == "" {
+		return r.actionTimer.deadline
+	}
+	timeout := time.Duration(r.opts.ActionTimeout) * time.Second
+	seq, playerId := r.actionTimer.seq, state.Active.ID
+	r.actionTimer.playerId = playerId
+	r.actionTimer.deadline = time.Now().Add(timeout)
+	r.actionTimer.timer = time.AfterFunc(timeout, func() {
+		r.handleActionTimeout(seq, playerId)
+	})
+	return r.actionTimer.deadline
+}
+
+func (r *room) stopActionTimer() {
+	r.actionTimer.Lock()
+	defer r.actionTimer.Unlock()
+	r.actionTimer.stop()
+}
+
+// drainTimeBank deducts the time the timed player has spent acting beyond
+// their ActionTimeout from their time bank. It must be called with the
+// actionTimer lock held.
+func (r *room) drainTimeBank() {
+	if r.actionTimer.bankStart.IsZero() {
+		return
+	}
+	used := int(math.Ceil(time.Since(r.actionTimer.bankStart).Seconds()))
+	r.actionTimer.bankStart = time.Time{}
+	r.playerMap.RLock()
+	defer r.playerMap.RUnlock()
+	player := r.playerMap.players[r.actionTimer.playerId]
+	if player == nil {
+		return
+	}
+	player.TimeBank -= used
+	if player.TimeBank < 0 {
+		player.TimeBank = 0
+	}
+}
+
+func (r *room) topUpTimeBanks() {
+	if r.opts.TimeBankTopUpHands <= 0 ||
+		r.handsPlayed%r.opts.TimeBankTopUpHands != 0 {
+		return
+	}
+	r.playerMap.RLock()
+	defer r.playerMap.RUnlock()
+	for _, player := range r.playerMap.players {
+		player.TimeBank += r.opts.TimeBankTopUp
+		if player.TimeBank > r.opts.TimeBank {
+			player.TimeBank = r.opts.TimeBank
+		}
+	}
+}
+
+// handleActionTimeout runs on the timer's own goroutine, so it locks the room
+// before anything else; otherwise the player could act, and the timer be
+// restarted for them, between the check of seq and the auto-action.
+func (r *room) handleActionTimeout(seq int, playerId string) {
+	r.Lock()
+	defer r.Unlock()
+	r.actionTimer.Lock()
+	if seq != r.actionTimer.seq {
+		// The player acted (or the hand ended) before the timer fired
+		r.actionTimer.Unlock()
+		return
+	}
+	r.actionTimer.timer = nil
+	r.playerMap.RLock()
+	player := r.playerMap.players[playerId]
+	r.playerMap.RUnlock()
+	if player == nil {
+		r.actionTimer.Unlock()
+		return
+	}
+	if r.actionTimer.bankStart.IsZero() && player.TimeBank > 0 {
+		// Base allowance is up; start drawing on the player's time bank
+		bank := time.Duration(player.TimeBank) * time.Second
+		r.actionTimer.bankStart = time.Now()
+		r.actionTimer.deadline = r.actionTimer.bankStart.Add(bank)
+		r.actionTimer.timer = time.AfterFunc(bank, func() {
+			r.handleActionTimeout(seq, playerId)
+		})
+		deadline := r.actionTimer.deadline
+		r.actionTimer.Unlock()
+		log.Printf("%s is using their time bank in room %s", playerId, r.id)
+		r.broadcast(types.ToPlayerMessage{
+			Type:           types.MessageTypeTimeBank,
+			PlayerId:       playerId,
+			ActionDeadline: deadline,
+		})
+		return
+	}
+	if !r.actionTimer.bankStart.IsZero() {
+		player.TimeBank = 0
+		r.actionTimer.bankStart = time.Time{}
+	}
+	r.actionTimer.Unlock()
+
+	if r.gameTable == nil || r.gameTable.Active().ID != playerId {
+		return
+	}
+	action := table.Action{Type: table.Fold}
+	if r.gameTable.State().Owed == 0 {
+		action.Type = table.Check
+	}
+	log.Printf(
+		"%s ran out of time to act in room %s; auto-%s",
+		playerId,
+		r.id,
+		action.Type.String())
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{
+			Type:   types.MessageTypePlayerAction,
+			Action: action,
+		},
+		player)
+}
@@ -0,0 +1,93 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// anteState tracks the antes posted in the current hand.
+type anteState struct {
+	posted map[string]int
+}
+
+func (opts *roomOpts) validateAnteType() error {
+	if opts.AnteType < types.AnteTypeClassic || opts.AnteType > types.AnteTypeButton {
+		return fmt.Errorf("unknown ante type %d", opts.AnteType)
+	}
+	return nil
+}
+
+// anteType is who the table should take the ante from.
+func (opts *roomOpts) anteType() table.AnteType {
+	switch opts.AnteType {
+	case types.AnteTypeBigBlind:
+		return table.BigBlindAnte
+	case types.AnteTypeButton:
+		return table.ButtonAnte
+	}
+	return table.ClassicAnte
+}
+
+// noteAntes must be called once a hand has been dealt, before it is recorded.
+// It notes the antes the table took: from everyone dealt in for a classic
+// ante, or the whole lot as dead money from the big blind or button.
+func (r *room) noteAntes(state table.State) {
+	r.antes = anteState{}
+	ante := state.Options.Stakes.Ante
+	if ante == 0 {
+		return
+	}
+	posted := make(map[string]int)
+	for _, seat := range state.Seats {
+		if seat.SittingOut {
+			continue
+		}
+		if state.Options.Stakes.AnteType != table.ClassicAnte {
+			if seat.DeadChips > 0 {
+				posted[seat.ID] = seat.DeadChips
+			}
+		} else if seat.ChipsInPot > 0 {
+			posted[seat.ID] = minInt(ante, seat.ChipsInPot)
+		}
+	}
+	r.antes.posted = posted
+}
+
+// chipsInPot returns what the player has put in the pot this hand, including
+// any ante they posted for everyone.
+func (r *room) chipsInPot(player table.Player) int {
+	return player.ChipsInPot + player.DeadChips
+}
+
+// withAntes adds any ante each player posted for everyone to their
+// contribution to the pot in the table state.
+func (r *room) withAntes(state table.State) table.State {
+	seats := make([]table.Player, len(state.Seats))
+	for i, seat := range state.Seats {
+		seats[i] = seat
+		seats[i].ChipsInPot = r.chipsInPot(seat)
+	}
+	state.Seats = seats
+	state.Active.ChipsInPot = r.chipsInPot(state.Active)
+	return state
+}
@@ -0,0 +1,74 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestNoteAntes(t *testing.T) {
+	// a is the big blind and b the button
+	tests := []struct {
+		name     string
+		anteType types.AnteType
+		posted   map[string]int
+		stacks   map[string]int
+	}{
+		{
+			name:     "classic",
+			anteType: types.AnteTypeClassic,
+			posted:   map[string]int{"a": 1, "b": 1, "c": 1},
+			stacks:   map[string]int{"a": 97, "b": 99, "c": 98},
+		},
+		{
+			name:     "big blind",
+			anteType: types.AnteTypeBigBlind,
+			posted:   map[string]int{"a": 3},
+			stacks:   map[string]int{"a": 95, "b": 100, "c": 99},
+		},
+		{
+			name:     "button",
+			anteType: types.AnteTypeButton,
+			posted:   map[string]int{"b": 3},
+			stacks:   map[string]int{"a": 98, "b": 97, "c": 99},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{Ante: 1, AnteType: tt.anteType}, "a", "b", "c")
+			state := r.gameTable.State()
+			if !reflect.DeepEqual(r.antes.posted, tt.posted) {
+				t.Errorf("posted = %v; want %v", r.antes.posted, tt.posted)
+			}
+			if got := stacks(state); !reflect.DeepEqual(got, tt.stacks) {
+				t.Errorf("stacks = %v; want %v", got, tt.stacks)
+			}
+			inPot := 0
+			for _, seat := range r.withAntes(state).Seats {
+				inPot += seat.ChipsInPot
+			}
+			if inPot != state.Pot {
+				t.Errorf("players put %d in; want the whole pot, %d", inPot, state.Pot)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/alcamerone/pocket2s/replay"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func (opts *roomOpts) validateBuyIn() error {
+	if opts.MinBuyIn == 0 && opts.MaxBuyIn == 0 {
+		return nil
+	}
+	if opts.Tournament != nil {
+		return errors.New("tournaments cannot have variable buy-ins")
+	}
+	if opts.MinBuyIn < 1 || opts.MaxBuyIn < opts.MinBuyIn {
+		return errors.New("invalid buy-in range")
+	}
+	if opts.BuyIn == 0 {
+		opts.BuyIn = opts.MaxBuyIn
+	}
+	if opts.BuyIn < opts.MinBuyIn || opts.BuyIn > opts.MaxBuyIn {
+		return errors.New("the default buy-in must be within the buy-in range")
+	}
+	return nil
+}
+
+// maxStack returns the most chips a player may buy or top up to.
+func (opts *roomOpts) maxStack() int {
+	if opts.MaxBuyIn > 0 {
+		return opts.MaxBuyIn
+	}
+	return opts.BuyIn
+}
+
+// buyInAmount checks the amount a player asked to buy in for, returning the
+// room's default buy-in if they didn't ask for one.
+func (r *room) buyInAmount(requested int) (int, error) {
+	if requested == 0 || requested == r.opts.BuyIn {
+		return r.opts.BuyIn, nil
+	}
+	if r.opts.MaxBuyIn == 0 {
+		return 0, fmt.Errorf("This room has a fixed buy-in of %d.", r.opts.BuyIn)
+	}
+	if requested < r.opts.MinBuyIn || requested > r.opts.MaxBuyIn {
+		return 0, fmt.Errorf(
+			"You can buy in for between %d and %d.",
+			r.opts.MinBuyIn,
+			r.opts.MaxBuyIn)
+	}
+	return requested, nil
+}
+
+// canBuyBackIn sends the player an error and returns false unless they can
+// buy back in: they must be at the table and out of chips, and no hand can
+// be in progress.
+func (r *room) canBuyBackIn(player *types.Player) bool {
+	pState := getPlayerState(player.Id, r.gameTable)
+	if pState.ID != player.Id {
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, you can only buy back in once you're at the table.")
+		return false
+	}
+	if !r.betweenHands() {
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, you can only buy back in between hands.")
+		return false
+	}
+	if pState.Chips > 0 {
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, you can only buy back in once you've run out of chips.")
+		return false
+	}
+	return true
+}
+
+// buyBackIn gives a broke player a new stack of the given size, and returns
+// the chips that added, which is what goes in the ledger.
+func (r *room) buyBackIn(playerId string, amount int) (int, error) {
+	before := getPlayerState(playerId, r.gameTable).Chips
+	if amount != r.opts.BuyIn {
+		r.setChips(playerId, amount)
+	} else {
+		err := r.gameTable.BuyPlayerIn(playerId)
+		if err != nil {
+			return 0, err
+		}
+		r.tableLog.Record(replay.Event{
+			Type:     replay.EventBuyIn,
+			PlayerId: playerId,
+		})
+	}
+	return getPlayerState(playerId, r.gameTable).Chips - before, nil
+}
+
+// topUp adds chips to a seated player's stack between hands, up to the
+// room's maximum buy-in.
+func (r *room) topUp(player *types.Player, amount int) {
+	if r.isTournament() {
+		r.sendError(player, types.ErrorCodeRebuyNotAllowed, "Sorry, you can't top up in a tournament.")
+		return
+	}
+	if r.gameTable == nil || getPlayerState(player.Id, r.gameTable).ID != player.Id {
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, you can only top up once you're at the table.")
+		return
+	}
+	if !r.betweenHands() {
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, you can only top up between hands.")
+		return
+	}
+	chips := getPlayerState(player.Id, r.gameTable).Chips
+	if amount < 1 || chips+amount > r.opts.maxStack() {
+		r.sendError(
+			player,
+			types.ErrorCodeBuyInOutOfRange,
+			fmt.Sprintf("You can top up by at most %d.", r.opts.maxStack()-chips))
+		return
+	}
+	r.setChips(player.Id, chips+amount)
+	r.recordLedger(LedgerEntryTopUp, player.Id, amount)
+	if chips == 0 {
+		player.Broke = false
+	}
+	log.Printf("%s has topped up by %d in room %s", player.Id, amount, r.id)
+}
+
+// isDealtIn reports whether the player has a seat at the table, or is
+// waiting to get back the stack they had before the server restarted.
+func (r *room) isDealtIn(playerId string) bool {
+	if _, restored := r.restoredChips[playerId]; restored {
+		return true
+	}
+	return r.gameTable != nil && getPlayerState(playerId, r.gameTable).ID == playerId
+}
+
+// takeBuyIn returns the amount the player asked to buy in for, and forgets
+// it now that they are being dealt in.
+func (r *room) takeBuyIn(playerId string) int {
+	amount, ok := r.buyIns[playerId]
+	if !ok {
+		return r.opts.BuyIn
+	}
+	delete(r.buyIns, playerId)
+	return amount
+}
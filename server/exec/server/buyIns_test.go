@@ -0,0 +1,130 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestTopUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		playerId string
+		amount   int
+		want     int
+	}{
+		// b and c fold, so a wins the blinds with their own big blind still
+		// in the pot, and c leaves their small blind behind
+		{name: "winner of the last hand", playerId: "a", amount: 50, want: 151},
+		{name: "loser of the last hand", playerId: "c", amount: 50, want: 149},
+		{name: "above the maximum buy-in", playerId: "b", amount: 150, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{MinBuyIn: 50, MaxBuyIn: 200}, "a", "b", "c")
+			act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+			r.handleMessageFromPlayer(
+				types.FromPlayerMessage{Type: types.MessageTypeTopUp, Amount: tt.amount},
+				r.playerMap.players[tt.playerId])
+			if got := stacks(r.gameTable.State())[tt.playerId]; got != tt.want {
+				t.Errorf("%s has %d chips; want %d", tt.playerId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuyBackIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		broke    bool
+		handOver bool
+		want     int
+		ledger   int
+	}{
+		{name: "mid-hand", broke: true, handOver: false, want: 0, ledger: 3},
+		{name: "with chips", broke: false, handOver: true, want: 99, ledger: 3},
+		{name: "broke between hands", broke: true, handOver: true, want: 150, ledger: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{MinBuyIn: 50, MaxBuyIn: 200}, "a", "b", "c")
+			if tt.handOver {
+				act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+			}
+			// c is the small blind, with a chip in the pot
+			if tt.broke {
+				r.setChips("c", 0)
+			}
+			r.handleMessageFromPlayer(
+				types.FromPlayerMessage{Type: types.MessageTypeBuyIn, Amount: 150},
+				r.playerMap.players["c"])
+			if got := stacks(r.gameTable.State())["c"]; got != tt.want {
+				t.Errorf("c has %d chips; want %d", got, tt.want)
+			}
+			if got := len(r.ledger.getEntries()); got != tt.ledger {
+				t.Errorf("%d ledger entries; want %d", got, tt.ledger)
+			}
+		})
+	}
+}
+
+func TestBuyBackInAdded(t *testing.T) {
+	tests := []struct {
+		name   string
+		chips  int
+		amount int
+		want   int
+	}{
+		{name: "broke", chips: 0, amount: 150, want: 150},
+		{name: "short stack", chips: 40, amount: 150, want: 110},
+		{name: "room buy-in", chips: 40, amount: 100, want: 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{MinBuyIn: 50, MaxBuyIn: 200}, "a", "b", "c")
+			act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+			r.setChips("c", tt.chips)
+			added, err := r.buyBackIn("c", tt.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if added != tt.want {
+				t.Errorf("added %d chips; want %d", added, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuyInBeforeDeal(t *testing.T) {
+	opts := roomOpts{BuyIn: 100, SmallBlind: 1, BigBlind: 2}
+	if err := opts.validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := newRoom(t.Name(), opts)
+	r.playerMap.players["a"] = &types.Player{Id: "a"}
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{Type: types.MessageTypeBuyIn, Amount: 100},
+		r.playerMap.players["a"])
+	if r.gameTable != nil || len(r.ledger.getEntries()) != 0 {
+		t.Error("bought in before the table was dealt")
+	}
+}
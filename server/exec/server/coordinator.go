@@ -0,0 +1,731 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+	"github.com/gocraft/web"
+)
+
+// coordinatorOpts configures a multi-table tournament. Room holds the options
+// every table is created with, and must include the tournament's options.
+type coordinatorOpts struct {
+	// TableSize is the most players seated at each table, defaulting to the
+	// room options' MaxPlayers
+	TableSize int
+	Room      roomOpts
+}
+
+// coordinator runs a tournament with more entrants than fit at one table. It
+// seats entrants across as few tables as possible, keeps the tables balanced
+// as players bust, and breaks tables until everyone left is at the final one.
+// Players keep their connection when they are moved between tables.
+type coordinator struct {
+	sync.Mutex
+	id       string
+	opts     coordinatorOpts
+	players  map[string]*types.Player
+	tables   map[string]*room
+	started  bool
+	finished bool
+	// entries counts the entrants plus any rebuys and add-ons, each of which
+	// pays the entry fee into the prize pool
+	entries int
+	// tablesOpened numbers the tables, so that broken tables' names are not
+	// reused
+	tablesOpened int
+	level        int
+	levelStart   time.Time
+	// levelHands holds the hands each table has played at the current level;
+	// the level is over once any table has played enough
+	levelHands map[string]int
+	// arriving counts the players on their way to each table, who have left
+	// their old table but haven't been seated at the new one yet
+	arriving map[string]int
+	// standings holds eliminated players, best finish first
+	standings []types.Standing
+	// rebuys is the tournament's rebuy window, shared by every table
+	rebuys *rebuyWindow
+}
+
+var (
+	coordinatorMap  = make(map[string]*coordinator)
+	coordinatorLock = sync.RWMutex{}
+)
+
+func (opts *coordinatorOpts) validate() error {
+	if opts.TableSize == 0 {
+		opts.TableSize = opts.Room.maxPlayers()
+	}
+	if opts.TableSize < MIN_TABLE_SIZE || opts.TableSize > MAX_TABLE_SIZE {
+		return fmt.Errorf(
+			"tables must seat between %d and %d players",
+			MIN_TABLE_SIZE,
+			MAX_TABLE_SIZE)
+	}
+	opts.Room.MaxPlayers = opts.TableSize
+	if opts.Room.Tournament == nil {
+		return errors.New("the room options must include a tournament")
+	}
+	return opts.Room.validate()
+}
+
+func handleCreateTournament(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	tournamentId := req.PathParams["tournamentId"]
+	var opts coordinatorOpts
+	reqBody, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %s", err.Error())
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(reqBody, &opts)
+	if err != nil {
+		log.Printf("Error unmarshalling request body: %s", err.Error())
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = opts.validate()
+	if err != nil {
+		log.Printf("Invalid options for tournament %s: %s", tournamentId, err.Error())
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	coordinatorLock.Lock()
+	defer coordinatorLock.Unlock()
+	if coordinatorMap[tournamentId] != nil {
+		log.Printf("error: a tournament named %s already exists", tournamentId)
+		rw.WriteHeader(http.StatusConflict)
+		return
+	}
+	coordinatorMap[tournamentId] = &coordinator{
+		id:         tournamentId,
+		opts:       opts,
+		players:    make(map[string]*types.Player),
+		tables:     make(map[string]*room),
+		levelHands: make(map[string]int),
+		arriving:   make(map[string]int),
+		rebuys:     &rebuyWindow{},
+	}
+	log.Printf("created tournament %s", tournamentId)
+	rw.WriteHeader(http.StatusCreated)
+}
+
+func getCoordinator(tournamentId string) *coordinator {
+	coordinatorLock.RLock()
+	defer coordinatorLock.RUnlock()
+	return coordinatorMap[tournamentId]
+}
+
+// handleTournamentConnect registers entrants before the tournament starts,
+// and reconnects them to whichever table they are at afterwards.
+func handleTournamentConnect(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	tournamentId := req.PathParams["tournamentId"]
+	c := getCoordinator(tournamentId)
+	if c == nil {
+		log.Printf("error: tournament %s does not exist", tournamentId)
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	playerId := req.PathParams["playerId"]
+	token := req.URL.Query().Get("token")
+
+	// A returning player's table has to be locked before the coordinator
+	r := c.lockPlayerTable(playerId)
+	if r != nil {
+		defer r.Unlock()
+	}
+	defer c.Unlock()
+	player, playerExists := c.players[playerId]
+	if playerExists && player.Conn != nil {
+		log.Printf("error: a player named %s is already in tournament %s", playerId, tournamentId)
+		rw.WriteHeader(http.StatusConflict)
+		return
+	}
+	if playerExists && !validSessionToken(tournamentId, player, token) {
+		log.Printf("error: invalid session token for %s in tournament %s", playerId, tournamentId)
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// Returning players have already proven they may join
+	if !playerExists || player.SessionNonce == "" {
+		if status := c.opts.Room.checkAccess(req.Request); status != 0 {
+			log.Printf("error: %s gave the wrong password or invite code for tournament %s", playerId, tournamentId)
+			rw.WriteHeader(status)
+			return
+		}
+	}
+	if !playerExists && c.started {
+		log.Printf("error: tournament %s has already started", tournamentId)
+		rw.WriteHeader(http.StatusLocked)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(rw, req.Request, nil)
+	if err != nil {
+		log.Printf("error establishing connection: %s", err.Error())
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if playerExists && r != nil {
+		r.playerMap.Lock()
+		r.rejoin(player, conn)
+		r.playerMap.Unlock()
+		log.Printf("%s has rejoined tournament %s", playerId, tournamentId)
+	} else if playerExists {
+		// Not seated yet, or knocked out
+		player.Conn = conn
+		log.Printf("%s has rejoined tournament %s", playerId, tournamentId)
+	} else {
+		player = &types.Player{
+			Id:         playerId,
+			Conn:       conn,
+			SittingOut: true,
+			TimeBank:   c.opts.Room.TimeBank,
+		}
+		token, err = newSessionToken(tournamentId, player)
+		if err != nil {
+			log.Printf("error issuing session token to %s: %s", playerId, err.Error())
+		}
+		c.players[playerId] = player
+		log.Printf("%s has entered tournament %s", playerId, tournamentId)
+	}
+	err = conn.WriteJSON(types.ToPlayerMessage{
+		Type:         types.MessageTypeHello,
+		SessionToken: token,
+		RoomId:       player.RoomId,
+	})
+	if err != nil {
+		log.Printf("error sending \"hello\" message to player: %s", err.Error())
+	}
+	if r != nil {
+		r.broadcast(types.ToPlayerMessage{
+			Type:     types.MessageTypePlayerConnected,
+			PlayerId: playerId,
+		})
+	}
+	go listenForPlayerMessages(player, nil)
+}
+
+// lockPlayerTable locks the table the player is seated at, if any, and then
+// the coordinator, since tables must always be locked first. It returns the
+// table, which the caller must unlock after the coordinator.
+func (c *coordinator) lockPlayerTable(playerId string) *room {
+	for {
+		c.Lock()
+		r := c.playerTable(playerId)
+		c.Unlock()
+		if r != nil {
+			r.Lock()
+		}
+		c.Lock()
+		// The player may have been moved in the meantime
+		if c.playerTable(playerId) == r {
+			return r
+		}
+		c.Unlock()
+		if r != nil {
+			r.Unlock()
+		}
+	}
+}
+
+// playerTable returns the table the player is seated at, or nil if they
+// aren't at one. The caller must hold the coordinator's lock.
+func (c *coordinator) playerTable(playerId string) *room {
+	player := c.players[playerId]
+	if player == nil {
+		return nil
+	}
+	return c.tables[player.RoomId]
+}
+
+func handleStartTournament(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	tournamentId := req.PathParams["tournamentId"]
+	c := getCoordinator(tournamentId)
+	if c == nil {
+		log.Printf("error: tournament %s does not exist", tournamentId)
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	err := c.start()
+	if err != nil {
+		log.Printf("error starting tournament %s: %s", tournamentId, err.Error())
+		rw.WriteHeader(http.StatusConflict)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// start seats the entrants at random across as few tables as will hold them,
+// as evenly as possible.
+func (c *coordinator) start() error {
+	c.Lock()
+	if c.started {
+		c.Unlock()
+		return errors.New("the tournament has already started")
+	}
+	if len(c.players) < 2 {
+		c.Unlock()
+		return errors.New("a tournament needs at least two entrants")
+	}
+	c.started = true
+	c.entries = len(c.players)
+	c.levelStart = time.Now()
+
+	playerIds := make([]string, 0, len(c.players))
+	for playerId := range c.players {
+		playerIds = append(playerIds, playerId)
+	}
+	rand.Shuffle(len(playerIds), func(i, j int) {
+		playerIds[i], playerIds[j] = playerIds[j], playerIds[i]
+	})
+	tableCount := c.tablesNeeded(len(playerIds))
+	tables := make([]*room, tableCount)
+	seating := make([][]*types.Player, tableCount)
+	for i := range tables {
+		tables[i] = c.openTable()
+	}
+	for i, playerId := range playerIds {
+		seating[i%tableCount] = append(seating[i%tableCount], c.players[playerId])
+	}
+	level := c.blindLevel()
+	log.Printf(
+		"tournament %s has started with %d entrants at %d tables",
+		c.id,
+		len(playerIds),
+		tableCount)
+	c.Unlock()
+
+	// Players can send their table messages as soon as they are seated, so
+	// each table is locked until everyone at it has been told where they are
+	for i, r := range tables {
+		r.Lock()
+		for _, player := range seating[i] {
+			r.seatPlayer(player, c.opts.Room.BuyIn)
+		}
+		r.broadcast(types.ToPlayerMessage{
+			Type:       types.MessageTypeLevelUp,
+			BlindLevel: level,
+		})
+		for _, player := range seating[i] {
+			c.sendTableChange(player)
+		}
+		r.Unlock()
+	}
+	return nil
+}
+
+func (c *coordinator) tablesNeeded(players int) int {
+	return (players + c.opts.TableSize - 1) / c.opts.TableSize
+}
+
+// openTable creates a room for a new table and adds it to the room map. The
+// caller must hold the coordinator's lock.
+func (c *coordinator) openTable() *room {
+	roomLock.Lock()
+	defer roomLock.Unlock()
+	var roomId string
+	for roomId == "" || roomMap[roomId] != nil {
+		c.tablesOpened++
+		roomId = fmt.Sprintf("%s-table-%d", c.id, c.tablesOpened)
+	}
+	r := newRoom(roomId, c.opts.Room)
+	r.coordinator = c
+	r.rebuys.window = c.rebuys
+	// Nobody may join a table directly, and the coordinator announces the
+	// blind levels, so the table's own tournament starts straight away
+	r.tournament.started = true
+	r.tournament.level = c.level
+	c.tables[roomId] = r
+	roomMap[roomId] = r
+	return r
+}
+
+// closeTable stops a table being used once everyone left at it has been
+// moved. The room itself is removed by updateTables. The caller must hold the
+// coordinator's lock.
+func (c *coordinator) closeTable(r *room) {
+	delete(c.tables, r.id)
+	delete(c.levelHands, r.id)
+	delete(c.arriving, r.id)
+}
+
+// closeFinishedTable forgets a table that has closed itself after the
+// tournament ended, and the tournament too once all its tables are gone.
+func (c *coordinator) closeFinishedTable(r *room) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.tables, r.id)
+	if len(c.tables) > 0 {
+		return
+	}
+	coordinatorLock.Lock()
+	defer coordinatorLock.Unlock()
+	delete(coordinatorMap, c.id)
+	log.Printf("tournament %s has been closed", c.id)
+}
+
+func (c *coordinator) isFinished() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.finished
+}
+
+func (c *coordinator) sendTableChange(player *types.Player) {
+	if player.Conn == nil {
+		return
+	}
+	err := sendToPlayer(player, types.ToPlayerMessage{
+		Type:   types.MessageTypeTableChange,
+		RoomId: player.RoomId,
+	})
+	if err != nil {
+		log.Printf("error telling %s about their new table: %s", player.Id, err.Error())
+	}
+}
+
+func (c *coordinator) isEliminated(playerId string) bool {
+	for _, standing := range c.standings {
+		if standing.PlayerId == playerId {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *coordinator) playersLeft() int {
+	return len(c.players) - len(c.standings)
+}
+
+// tableCount returns the number of players in the tournament at the given
+// table, counting those on their way to it.
+func (c *coordinator) tableCount(r *room) int {
+	return len(c.activePlayers(r)) + c.arriving[r.id]
+}
+
+// activePlayers returns the players still in the tournament at the given
+// table, in seat order.
+func (c *coordinator) activePlayers(r *room) []*types.Player {
+	playerIds := r.getPlayerIds()
+	players := make([]*types.Player, 0, len(playerIds))
+	for _, playerId := range playerIds {
+		if !c.isEliminated(playerId) {
+			players = append(players, c.players[playerId])
+		}
+	}
+	return players
+}
+
+// tableUpdate is what the tables need to hear after one of them finishes a
+// hand.
+type tableUpdate struct {
+	level    int
+	finished bool
+	// msgs are sent to every table
+	msgs []types.ToPlayerMessage
+	// tables holds every table other than the one that finished its hand
+	tables []*room
+	moves  []tableMove
+	// closed is the table that finished its hand, if it has been broken
+	closed *room
+}
+
+// tableMove is a player on their way to another table, and the chips they are
+// taking with them.
+type tableMove struct {
+	player *types.Player
+	chips  int
+	to     *room
+}
+
+// endHand is called by a table once its hand is over, with the table locked.
+// It eliminates anyone who has busted, finishes the tournament once a single
+// player is left, and otherwise moves the blinds up if the level is over and
+// rebalances the tables. Only the table that has just finished its hand has
+// players taken from it, since everyone else may be mid-hand. The other tables
+// are updated from a goroutine of their own, since no table may be locked
+// while another one is.
+func (c *coordinator) endHand(r *room, state table.State) {
+	update := c.planEndHand(r, state)
+	if update == nil {
+		return
+	}
+	r.tournament.level = update.level
+	r.tournament.finished = update.finished
+	for _, msg := range update.msgs {
+		r.broadcast(msg)
+	}
+	for _, move := range update.moves {
+		r.broadcast(types.ToPlayerMessage{
+			Type:     types.MessageTypePlayerDisconnected,
+			PlayerId: move.player.Id,
+		})
+	}
+	if update.closed != nil {
+		r.stopActionTimer()
+	}
+	if update.finished {
+		go r.closeIfEmpty()
+	}
+	go c.updateTables(update)
+}
+
+// planEndHand works out what happens after a table's hand, taking any
+// players who must move out of the table. It returns nil if the tournament is
+// already over.
+func (c *coordinator) planEndHand(r *room, state table.State) *tableUpdate {
+	c.Lock()
+	defer c.Unlock()
+	if c.finished {
+		return nil
+	}
+	update := &tableUpdate{}
+	busted := make([]string, 0)
+	for _, seat := range state.Seats {
+		if seat.Chips == 0 && !c.isEliminated(seat.ID) && !r.canRebuy(seat.ID) {
+			busted = append(busted, seat.ID)
+		}
+	}
+	r.sortBusted(busted)
+	playersLeft := c.playersLeft()
+	for i, playerId := range busted {
+		standing := c.addStanding(playerId, playersLeft-i)
+		log.Printf("%s finished in place %d in tournament %s", playerId, standing.Place, c.id)
+		update.msgs = append(update.msgs, types.ToPlayerMessage{
+			Type:      types.MessageTypePlayerEliminated,
+			PlayerId:  playerId,
+			Standings: []types.Standing{standing},
+		})
+	}
+	if c.playersLeft() <= 1 {
+		c.finish()
+		update.msgs = append(update.msgs, types.ToPlayerMessage{
+			Type:      types.MessageTypeTournamentOver,
+			Standings: c.standings,
+		})
+	} else {
+		c.levelHands[r.id]++
+		if c.checkLevel() {
+			update.msgs = append(update.msgs, types.ToPlayerMessage{
+				Type:       types.MessageTypeLevelUp,
+				BlindLevel: c.blindLevel(),
+			})
+		}
+		update.moves = c.balance(r)
+		if c.tables[r.id] == nil {
+			update.closed = r
+		}
+	}
+	update.level = c.level
+	update.finished = c.finished
+	for roomId, other := range c.tables {
+		if roomId != r.id {
+			update.tables = append(update.tables, other)
+		}
+	}
+	return update
+}
+
+// updateTables passes on a table's update to every other table, and seats
+// the players moving between tables, locking one table at a time.
+func (c *coordinator) updateTables(update *tableUpdate) {
+	for _, r := range update.tables {
+		r.Lock()
+		// Updates from different tables may arrive out of order
+		if update.level > r.tournament.level {
+			r.tournament.level = update.level
+		}
+		if update.finished {
+			r.tournament.finished = true
+		}
+		for _, msg := range update.msgs {
+			r.broadcast(msg)
+		}
+		r.Unlock()
+	}
+	for _, move := range update.moves {
+		move.to.Lock()
+		move.to.seatPlayer(move.player, move.chips)
+		move.to.broadcast(types.ToPlayerMessage{
+			Type:     types.MessageTypePlayerConnected,
+			PlayerId: move.player.Id,
+		})
+		// The new table writes to the player's connection too
+		c.sendTableChange(move.player)
+		move.to.Unlock()
+		c.Lock()
+		c.arriving[move.to.id]--
+		c.Unlock()
+		log.Printf("moved %s to %s with %d chips", move.player.Id, move.to.id, move.chips)
+	}
+	if update.closed != nil {
+		roomLock.Lock()
+		delete(roomMap, update.closed.id)
+		roomLock.Unlock()
+		log.Printf("table %s in tournament %s has been broken", update.closed.id, c.id)
+	}
+	if update.finished {
+		for _, r := range update.tables {
+			go r.closeIfEmpty()
+		}
+	}
+}
+
+// finish records the winner. The caller must hold the coordinator's lock.
+func (c *coordinator) finish() {
+	for playerId := range c.players {
+		if !c.isEliminated(playerId) {
+			c.addStanding(playerId, 1)
+		}
+	}
+	c.finished = true
+	log.Printf("tournament %s is over", c.id)
+}
+
+// checkLevel moves the tournament up to the next blind level once the current
+// one has run its course, and reports whether it did. The new blinds take
+// effect from each table's next hand. The caller must hold the coordinator's
+// lock.
+func (c *coordinator) checkLevel() bool {
+	levels := c.opts.Room.Tournament.Levels
+	if c.level >= len(levels)-1 {
+		return false
+	}
+	level := levels[c.level]
+	handsUp := false
+	for _, hands := range c.levelHands {
+		if level.Hands > 0 && hands >= level.Hands {
+			handsUp = true
+		}
+	}
+	timeUp := level.Minutes > 0 &&
+		time.Since(c.levelStart) >= time.Duration(level.Minutes)*time.Minute
+	if !handsUp && !timeUp {
+		return false
+	}
+	c.level++
+	c.levelStart = time.Now()
+	c.levelHands = make(map[string]int, len(c.tables))
+	log.Printf("tournament %s is moving up to blind level %d", c.id, c.level+1)
+	return true
+}
+
+func (c *coordinator) blindLevel() *types.BlindLevel {
+	level := c.opts.Room.Tournament.Levels[c.level]
+	return &types.BlindLevel{
+		Level:      c.level + 1,
+		SmallBlind: level.SmallBlind,
+		BigBlind:   level.BigBlind,
+		Ante:       level.Ante,
+	}
+}
+
+// balance breaks the given table if the remaining players fit at one fewer,
+// and otherwise moves players from it to the shortest table until the two
+// differ by at most one. A table that players are still on their way to isn't
+// broken. The caller must hold the coordinator's lock and the table's.
+func (c *coordinator) balance(r *room) []tableMove {
+	players := c.activePlayers(r)
+	moves := make([]tableMove, 0)
+	if len(c.tables) > c.tablesNeeded(c.playersLeft()) && c.arriving[r.id] == 0 {
+		for _, player := range players {
+			moves = append(moves, c.movePlayer(player, r, c.shortestTable(r)))
+		}
+		c.closeTable(r)
+		if len(c.tables) == 1 {
+			log.Printf("tournament %s is down to its final table", c.id)
+		}
+		return moves
+	}
+	for len(players) > 0 {
+		shortest := c.shortestTable(r)
+		if shortest == nil || c.tableCount(r)-c.tableCount(shortest) < 2 {
+			break
+		}
+		// Move whoever was seated last, to disturb the table the least
+		moves = append(moves, c.movePlayer(players[len(players)-1], r, shortest))
+		players = players[:len(players)-1]
+	}
+	return moves
+}
+
+// shortestTable returns the table other than the given one with the fewest
+// players left in the tournament, or nil if there are no other tables.
+func (c *coordinator) shortestTable(exclude *room) *room {
+	tableIds := make([]string, 0, len(c.tables))
+	for roomId := range c.tables {
+		if roomId != exclude.id {
+			tableIds = append(tableIds, roomId)
+		}
+	}
+	// Break ties the same way every time
+	sort.Strings(tableIds)
+	var shortest *room
+	shortestCount := 0
+	for _, roomId := range tableIds {
+		count := c.tableCount(c.tables[roomId])
+		if shortest == nil || count < shortestCount {
+			shortest = c.tables[roomId]
+			shortestCount = count
+		}
+	}
+	return shortest
+}
+
+// movePlayer takes a player and their chips away from a table that has just
+// finished its hand. They are seated at the other table by updateTables. The
+// caller must hold the coordinator's lock and the lock of the table the
+// player is leaving.
+func (c *coordinator) movePlayer(player *types.Player, from *room, to *room) tableMove {
+	chips := from.removePlayer(player.Id)
+	c.arriving[to.id]++
+	return tableMove{player: player, chips: chips, to: to}
+}
+
+func (c *coordinator) addEntry() {
+	c.Lock()
+	defer c.Unlock()
+	c.entries++
+}
+
+// addStanding records a player finishing in the given place, and returns
+// their standing including any payout.
+func (c *coordinator) addStanding(playerId string, place int) types.Standing {
+	standing := types.Standing{
+		PlayerId: playerId,
+		Place:    place,
+		Payout:   c.opts.Room.Tournament.payout(c.entries, place),
+	}
+	c.standings = append([]types.Standing{standing}, c.standings...)
+	return standing
+}
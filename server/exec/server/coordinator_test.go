@@ -0,0 +1,180 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func newTestCoordinator(t *testing.T, tableSize int, playerIds ...string) *coordinator {
+	t.Helper()
+	opts := coordinatorOpts{
+		TableSize: tableSize,
+		Room: roomOpts{
+			BuyIn:      100,
+			SmallBlind: 1,
+			BigBlind:   2,
+			Tournament: &tournamentOpts{
+				Levels:   []blindLevel{{SmallBlind: 1, BigBlind: 2}},
+				EntryFee: 10,
+				Payouts:  []int{100},
+			},
+		},
+	}
+	err := opts.validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &coordinator{
+		id:         t.Name(),
+		opts:       opts,
+		players:    make(map[string]*types.Player),
+		tables:     make(map[string]*room),
+		levelHands: make(map[string]int),
+		arriving:   make(map[string]int),
+		rebuys:     &rebuyWindow{},
+	}
+	for _, playerId := range playerIds {
+		c.players[playerId] = &types.Player{Id: playerId, SittingOut: true}
+	}
+	coordinatorLock.Lock()
+	coordinatorMap[c.id] = c
+	coordinatorLock.Unlock()
+	err = c.start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// endTestHand ends a hand at the table with the given stacks, and waits for
+// the other tables to be updated.
+func endTestHand(t *testing.T, c *coordinator, r *room, chips map[string]int) {
+	t.Helper()
+	state := table.State{}
+	for _, playerId := range r.getPlayerIds() {
+		state.Seats = append(state.Seats, table.Player{ID: playerId, Chips: chips[playerId]})
+	}
+	r.Lock()
+	c.endHand(r, state)
+	r.Unlock()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		c.Lock()
+		arriving := 0
+		for _, count := range c.arriving {
+			arriving += count
+		}
+		c.Unlock()
+		if arriving == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("players were never seated at their new tables")
+		}
+	}
+}
+
+func TestBreakTable(t *testing.T) {
+	c := newTestCoordinator(t, 3, "a", "b", "c", "d")
+	if len(c.tables) != 2 {
+		t.Fatalf("%d tables opened; want 2", len(c.tables))
+	}
+	broken := c.tables[c.players["a"].RoomId]
+	var survivor string
+	for _, playerId := range broken.getPlayerIds() {
+		if playerId != "a" {
+			survivor = playerId
+		}
+	}
+	endTestHand(t, c, broken, map[string]int{survivor: 100})
+
+	if !c.isEliminated("a") {
+		t.Error("a wasn't eliminated")
+	}
+	if len(c.tables) != 1 {
+		t.Fatalf("%d tables left; want 1", len(c.tables))
+	}
+	roomLock.RLock()
+	_, open := roomMap[broken.id]
+	roomLock.RUnlock()
+	if open {
+		t.Errorf("broken table %s is still open", broken.id)
+	}
+	var final *room
+	for _, r := range c.tables {
+		final = r
+	}
+	if got := len(c.activePlayers(final)); got != 3 {
+		t.Errorf("%d players at the final table; want 3", got)
+	}
+	// No hand has been dealt at the final table, so the survivor's stack
+	// waits to be applied when it is
+	if c.players[survivor].RoomId != final.id || final.restoredChips[survivor] != 100 {
+		t.Errorf("%s is at %s with %d chips; want %s with 100",
+			survivor, c.players[survivor].RoomId, final.restoredChips[survivor], final.id)
+	}
+
+	endTestHand(t, c, final, map[string]int{survivor: 300})
+	if !c.isFinished() || len(c.standings) != 4 || c.standings[0].PlayerId != survivor {
+		t.Fatalf("standings = %+v; want %s to win", c.standings, survivor)
+	}
+	if !final.tournament.finished {
+		t.Error("the final table doesn't know the tournament is over")
+	}
+	c.closeFinishedTable(final)
+	if getCoordinator(c.id) != nil {
+		t.Error("the tournament wasn't closed with its last table")
+	}
+}
+
+func TestLockPlayerTable(t *testing.T) {
+	c := newTestCoordinator(t, 3, "a", "b", "c", "d")
+	tests := []struct {
+		name     string
+		playerId string
+		want     *room
+	}{
+		{name: "seated", playerId: "a", want: c.tables[c.players["a"].RoomId]},
+		{name: "not entered", playerId: "e", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := c.lockPlayerTable(tt.playerId)
+			if r != tt.want {
+				t.Errorf("locked %v; want %v", r, tt.want)
+			}
+			if c.TryLock() {
+				t.Error("the coordinator wasn't locked")
+				c.Unlock()
+			}
+			if r != nil && r.TryLock() {
+				t.Error("the table wasn't locked")
+				r.Unlock()
+			}
+			c.Unlock()
+			if r != nil {
+				r.Unlock()
+			}
+		})
+	}
+}
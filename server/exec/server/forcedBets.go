@@ -0,0 +1,187 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/replay"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func (opts *roomOpts) validateForcedBets() error {
+	if opts.BombPotEvery < 0 || opts.BombPotAnte < 0 {
+		return errors.New("bomb pot options must not be negative")
+	}
+	if opts.BombPotEvery > 0 {
+		if opts.BombPotAnte == 0 {
+			return errors.New("bomb pots need an ante")
+		}
+		if opts.Tournament != nil {
+			return errors.New("tournaments cannot have bomb pots")
+		}
+	}
+	return nil
+}
+
+// isBombPot reports whether the current hand, or the next one if none is in
+// progress, is a bomb pot.
+func (r *room) isBombPot() bool {
+	every := r.opts.BombPotEvery
+	return every > 0 && (r.handsPlayed+1)%every == 0
+}
+
+// bombPotStakes are the stakes a bomb pot is dealt with. Both blinds post the
+// ante, and everyone else calls it before the flop.
+func (r *room) bombPotStakes() table.Stakes {
+	return table.Stakes{
+		BigBlind:   r.opts.BombPotAnte,
+		SmallBlind: r.opts.BombPotAnte,
+	}
+}
+
+// requestStraddle opts the player in to straddling the next hand. Only the
+// player who will be under the gun when it is dealt can straddle.
+func (r *room) requestStraddle(player *types.Player) {
+	if !r.opts.Straddle {
+		r.sendError(player, types.ErrorCodeStraddleNotAllowed, "Sorry, straddles aren't allowed in this room.")
+		return
+	}
+	if !r.betweenHands() {
+		r.sendError(player, types.ErrorCodeStraddleNotAllowed, "Sorry, you can only straddle before the deal.")
+		return
+	}
+	if r.underTheGunNextHand() != player.Id {
+		r.sendError(player, types.ErrorCodeStraddleNotAllowed, "Sorry, only the player under the gun can straddle.")
+		return
+	}
+	r.straddles[player.Id] = true
+	log.Printf("%s wants to straddle the next hand in room %s", player.Id, r.id)
+}
+
+// underTheGunNextHand returns the player who will be first to act pre-flop
+// in the next hand, or "" if there is no one to straddle: before the first
+// hand, or with fewer than three players dealt in.
+func (r *room) underTheGunNextHand() string {
+	if r.gameTable == nil {
+		return ""
+	}
+	sittingOut := make(map[string]bool)
+	for _, id := range r.getPlayersSittingOut() {
+		sittingOut[id] = true
+	}
+	seats := r.gameTable.Seats()
+	dealtIn := 0
+	for _, seat := range seats {
+		if seat.Chips > 0 && !sittingOut[seat.ID] {
+			dealtIn++
+		}
+	}
+	if dealtIn < 3 {
+		return ""
+	}
+	// Follow the table moving the button on to the next player dealt in,
+	// then past both blinds
+	seat := r.gameTable.State().Button
+	for moves := 0; moves < 4; {
+		seat = (seat + 1) % len(seats)
+		if seats[seat].Chips > 0 && !sittingOut[seats[seat].ID] {
+			moves++
+		}
+	}
+	return seats[seat].ID
+}
+
+// startForcedBets must be called once a hand has been dealt and recorded. It
+// plays out the pre-flop betting of a bomb pot, or posts a straddle for the
+// player under the gun if they asked to, and returns the resulting state.
+func (r *room) startForcedBets(state table.State) table.State {
+	straddles := r.straddles
+	r.straddles = make(map[string]bool)
+	if r.isBombPot() {
+		return r.playBombPotPreFlop(state)
+	}
+	if !straddles[state.Active.ID] {
+		return state
+	}
+	return r.postStraddle(state)
+}
+
+// playBombPotPreFlop calls or checks the ante on everyone's behalf, so that
+// the hand starts on the flop.
+func (r *room) playBombPotPreFlop(state table.State) table.State {
+	log.Printf("dealing a bomb pot in room %s", r.id)
+	for state.Status != table.Done && state.Round == table.PreFlop {
+		action := table.Action{Type: table.Check}
+		if state.Owed > 0 {
+			action = table.Action{Type: table.Call}
+		}
+		next, err := r.forceAction(action)
+		if err != nil {
+			log.Printf("error playing bomb pot ante for %s: %s", state.Active.ID, err.Error())
+			return state
+		}
+		state = next
+	}
+	return state
+}
+
+// postStraddle raises the big blind by itself on behalf of the player under
+// the gun, who still gets the option of raising again if everyone calls.
+func (r *room) postStraddle(state table.State) table.State {
+	if len(state.Seats) < 3 || r.currentGame().Limit == types.LimitFixedLimit {
+		return state
+	}
+	if !r.gameTable.CanStraddle() {
+		return state
+	}
+	next, err := r.forceAction(table.Action{Type: table.Straddle})
+	if err != nil {
+		log.Printf("error posting straddle for %s: %s", state.Active.ID, err.Error())
+		return state
+	}
+	log.Printf("%s has straddled in room %s", state.Active.ID, r.id)
+	return next
+}
+
+// forceAction makes an action on behalf of the active player as part of
+// dealing a hand, and records it like any other.
+func (r *room) forceAction(action table.Action) (table.State, error) {
+	before := r.gameTable.State()
+	state, err := r.gameTable.Act(action)
+	if err != nil {
+		return before, err
+	}
+	playerId := before.Active.ID
+	r.countRaise(action, before)
+	r.noteAction(playerId, action, before)
+	r.history.RecordAction(playerId, action, before, state)
+	r.tableLog.Record(replay.Event{
+		Type:     replay.EventAction,
+		PlayerId: playerId,
+		Action:   action,
+	})
+	r.broadcast(types.ToPlayerMessage{
+		Type:         types.MessageTypePlayerAction,
+		PlayerAction: types.PlayerAction{Action: action, PlayerId: playerId},
+	})
+	return state, nil
+}
@@ -0,0 +1,54 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestStraddle(t *testing.T) {
+	r := newTestRoom(t, roomOpts{Straddle: true}, "a", "b", "c")
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	// The button moves on to c, who is first to act with three players
+	for _, playerId := range []string{"a", "b", "c"} {
+		r.handleMessageFromPlayer(
+			types.FromPlayerMessage{Type: types.MessageTypeStraddle},
+			r.playerMap.players[playerId])
+	}
+	if len(r.straddles) != 1 || !r.straddles["c"] {
+		t.Fatalf("straddles = %v; want only c's", r.straddles)
+	}
+	for _, playerId := range []string{"a", "b", "c"} {
+		sendReady(r, playerId)
+	}
+	state := r.gameTable.State()
+	if state.Cost != 4 || state.Active.ID != "a" {
+		t.Fatalf("cost = %d, active = %s; want a straddle to 4 with a to act", state.Cost, state.Active.ID)
+	}
+	if min := r.minRaise(table.PreFlop); min != 4 {
+		t.Errorf("minimum raise = %d; want the straddle, 4", min)
+	}
+	act(t, r, table.Action{Type: table.Call}, table.Action{Type: table.Call})
+	if state = r.gameTable.State(); state.Round != table.PreFlop || state.Active.ID != "c" {
+		t.Errorf("round = %s, active = %s; want the straddler's option", state.Round, state.Active.ID)
+	}
+}
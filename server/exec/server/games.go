@@ -0,0 +1,184 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/randSource"
+	"github.com/alcamerone/pocket2s/replay"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// gameRotation tracks a mixed-game room's progress through its list of games.
+type gameRotation struct {
+	index int
+	hands int
+}
+
+func (opts *roomOpts) validateGames() error {
+	for _, game := range opts.Games {
+		if game.Variant != table.TexasHoldem && game.Variant != table.OmahaHi {
+			return fmt.Errorf("unknown variant %d", game.Variant)
+		}
+	}
+	if opts.RotateEvery < 0 {
+		return fmt.Errorf("can't rotate games every %d hands", opts.RotateEvery)
+	}
+	return nil
+}
+
+// currentGame returns the game being played: the room's own variant and limit
+// unless it rotates through a list of games.
+func (r *room) currentGame() types.Game {
+	if len(r.opts.Games) == 0 {
+		return types.Game{Variant: r.opts.Variant, Limit: r.opts.Limit}
+	}
+	return r.opts.Games[r.rotation.index%len(r.opts.Games)]
+}
+
+// advanceGame counts a finished hand towards the current game, moving on to
+// the next game once it has been played for RotateEvery hands, or for an orbit
+// of the table if RotateEvery is zero.
+func (r *room) advanceGame(state table.State) {
+	if len(r.opts.Games) < 2 {
+		return
+	}
+	r.rotation.hands++
+	handsPerGame := r.opts.RotateEvery
+	if handsPerGame == 0 {
+		for _, seat := range state.Seats {
+			if !seat.SittingOut {
+				handsPerGame++
+			}
+		}
+	}
+	if r.rotation.hands < handsPerGame {
+		return
+	}
+	r.rotation.index = (r.rotation.index + 1) % len(r.opts.Games)
+	r.rotation.hands = 0
+	log.Printf("room %s is switching to %s", r.id, r.currentGame().String())
+}
+
+// gameChanged reports whether the current game or stakes need a different
+// table to the one in play.
+func (r *room) gameChanged() bool {
+	current := r.gameTable.State().Options
+	next := r.tableOptions()
+	return current.Variant != next.Variant ||
+		current.Limit != next.Limit ||
+		current.Stakes != next.Stakes
+}
+
+// newTable replaces the room's table with a new one, dealing the first hand
+// from the given seed.
+func (r *room) newTable(seed []byte, playerIds []string, sittingOut []string) error {
+	var err error
+	r.randSrc, err = randSource.NewCryptoSource(seed)
+	if err != nil {
+		return err
+	}
+	dealer := hand.NewDealer(rand.New(r.randSrc))
+	opts := r.tableOptions()
+	r.gameTable = table.New(dealer, opts, playerIds, sittingOut)
+	r.tableLog.Start(opts, playerIds, sittingOut, seed)
+	r.publicSeeds = make(map[string]bool)
+	return nil
+}
+
+// rebuildTable replaces the table between hands with one for the current
+// game, keeping everyone's stacks and moving the button on as NewRound would.
+func (r *room) rebuildTable(seed []byte) error {
+	prev := r.gameTable.State()
+	stacks := make(map[string]int, len(prev.Seats))
+	for _, seat := range prev.Seats {
+		stacks[seat.ID] = seat.Chips
+	}
+	sittingOut := r.getPlayersSittingOut()
+	for _, seat := range prev.Seats {
+		if seat.Chips == 0 && !seat.SittingOut {
+			sittingOut = append(sittingOut, seat.ID)
+		}
+	}
+	// A new table gives the button to its second seat, so rotate the seating
+	// to put the player after the last button there
+	playerIds := r.getPlayerIds()
+	start := 0
+	for i, id := range playerIds {
+		if id == prev.Dealer.ID {
+			start = i
+		}
+	}
+	playerIds = append(playerIds[start:], playerIds[:start]...)
+
+	err := r.newTable(seed, playerIds, sittingOut)
+	if err != nil {
+		return err
+	}
+	r.seatsChanged = false
+	r.setStacks(stacks)
+	return nil
+}
+
+// setStacks sets players' stacks on a newly dealt table. The table has
+// already taken blinds and antes from the buy-in it gave each player, so those
+// are deducted from the stacks being set.
+func (r *room) setStacks(stacks map[string]int) {
+	for _, seat := range r.gameTable.Seats() {
+		stack, ok := stacks[seat.ID]
+		if !ok {
+			continue
+		}
+		chips := stack - seat.ChipsInPot - seat.DeadChips
+		if chips < 0 {
+			chips = 0
+		}
+		err := r.gameTable.SetPlayerChips(seat.ID, chips)
+		if err != nil {
+			log.Printf("error setting chips for %s: %s", seat.ID, err.Error())
+			continue
+		}
+		r.tableLog.Record(replay.Event{
+			Type:     replay.EventSetChips,
+			PlayerId: seat.ID,
+			Chips:    chips,
+		})
+	}
+}
+
+// setChips sets the chips a player has behind, leaving alone whatever they
+// have in the pot. Use it rather than setStacks for buying in or topping up
+// at a table that has already dealt.
+func (r *room) setChips(playerId string, chips int) {
+	err := r.gameTable.SetPlayerChips(playerId, chips)
+	if err != nil {
+		log.Printf("error setting chips for %s: %s", playerId, err.Error())
+		return
+	}
+	r.tableLog.Record(replay.Event{
+		Type:     replay.EventSetChips,
+		PlayerId: playerId,
+		Chips:    chips,
+	})
+}
@@ -0,0 +1,58 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetStacks(t *testing.T) {
+	tests := []struct {
+		name   string
+		stacks map[string]int
+		want   map[string]int
+	}{
+		{
+			// a has posted the big blind and c the small blind
+			name:   "blinds come out of the stacks",
+			stacks: map[string]int{"a": 50, "b": 50, "c": 50},
+			want:   map[string]int{"a": 48, "b": 50, "c": 49},
+		},
+		{
+			name:   "players not given a stack keep theirs",
+			stacks: map[string]int{"b": 150},
+			want:   map[string]int{"a": 98, "b": 150, "c": 99},
+		},
+		{
+			name:   "stacks smaller than the blinds go all in",
+			stacks: map[string]int{"a": 1},
+			want:   map[string]int{"a": 0, "b": 100, "c": 99},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+			r.setStacks(tt.stacks)
+			if got := stacks(r.gameTable.State()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stacks = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
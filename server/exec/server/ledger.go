@@ -0,0 +1,226 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gocraft/web"
+)
+
+type LedgerEntryType int
+
+const (
+	LedgerEntryUnknown LedgerEntryType = iota
+	LedgerEntryBuyIn
+	LedgerEntryRebuy
+	LedgerEntryAddOn
+	LedgerEntryCashOut
+	LedgerEntryTopUp
+)
+
+// LedgerEntry records chips changing hands between a player and the bank.
+type LedgerEntry struct {
+	Type     LedgerEntryType
+	PlayerId string
+	Amount   int
+	Time     time.Time
+}
+
+// LedgerResult sums up a player's session. Stack is what they have in front
+// of them now, and Net is what they are up or down, counting that stack as if
+// it were cashed out.
+type LedgerResult struct {
+	PlayerId  string
+	BoughtIn  int
+	CashedOut int
+	Stack     int
+	Net       int
+}
+
+// Transfer is a payment one player owes another to settle up.
+type Transfer struct {
+	From   string
+	To     string
+	Amount int
+}
+
+type LedgerReport struct {
+	Entries    []LedgerEntry
+	Results    []LedgerResult
+	Settlement []Transfer
+}
+
+// ledger keeps track of every buy-in, rebuy, add-on and cash-out in a room,
+// so that players can settle up at the end of the session.
+type ledger struct {
+	sync.Mutex
+	entries []LedgerEntry
+}
+
+func (l *ledger) record(entryType LedgerEntryType, playerId string, amount int) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, LedgerEntry{
+		Type:     entryType,
+		PlayerId: playerId,
+		Amount:   amount,
+		Time:     time.Now(),
+	})
+}
+
+func (l *ledger) getEntries() []LedgerEntry {
+	l.Lock()
+	defer l.Unlock()
+	return append([]LedgerEntry(nil), l.entries...)
+}
+
+// recordLedger adds an entry to the room's ledger. Tournaments pay out by
+// standings instead, so have no ledger.
+func (r *room) recordLedger(entryType LedgerEntryType, playerId string, amount int) {
+	if r.isTournament() || amount == 0 {
+		return
+	}
+	r.ledger.record(entryType, playerId, amount)
+}
+
+// currentStack returns the chips the player has in front of them.
+func (r *room) currentStack(playerId string) int {
+	if chips, ok := r.restoredChips[playerId]; ok {
+		return chips
+	}
+	if r.gameTable != nil {
+		pState := getPlayerState(playerId, r.gameTable)
+		if pState.ID == playerId {
+			return pState.Chips
+		}
+	}
+	return 0
+}
+
+func (r *room) ledgerReport() LedgerReport {
+	entries := r.ledger.getEntries()
+	results := make(map[string]*LedgerResult)
+	for _, entry := range entries {
+		result := results[entry.PlayerId]
+		if result == nil {
+			result = &LedgerResult{PlayerId: entry.PlayerId}
+			results[entry.PlayerId] = result
+		}
+		if entry.Type == LedgerEntryCashOut {
+			result.CashedOut += entry.Amount
+		} else {
+			result.BoughtIn += entry.Amount
+		}
+	}
+	r.playerMap.RLock()
+	defer r.playerMap.RUnlock()
+	report := LedgerReport{
+		Entries: entries,
+		Results: make([]LedgerResult, 0, len(results)),
+	}
+	for playerId, result := range results {
+		if _, seated := r.playerMap.players[playerId]; seated {
+			result.Stack = r.currentStack(playerId)
+		}
+		result.Net = result.CashedOut + result.Stack - result.BoughtIn
+		report.Results = append(report.Results, *result)
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Net > report.Results[j].Net
+	})
+	report.Settlement = settle(report.Results)
+	return report
+}
+
+// settle works out who should pay whom to square everyone's results, by
+// repeatedly having the biggest loser pay the biggest winner. Each transfer
+// settles at least one player, so there is at most one fewer transfer than
+// there are players who are up or down.
+func settle(results []LedgerResult) []Transfer {
+	type balance struct {
+		playerId string
+		amount   int
+	}
+	winners := make([]balance, 0)
+	losers := make([]balance, 0)
+	for _, result := range results {
+		if result.Net > 0 {
+			winners = append(winners, balance{result.PlayerId, result.Net})
+		} else if result.Net < 0 {
+			losers = append(losers, balance{result.PlayerId, -result.Net})
+		}
+	}
+	transfers := make([]Transfer, 0)
+	for len(winners) > 0 && len(losers) > 0 {
+		sort.Slice(winners, func(i, j int) bool { return winners[i].amount > winners[j].amount })
+		sort.Slice(losers, func(i, j int) bool { return losers[i].amount > losers[j].amount })
+		amount := minInt(winners[0].amount, losers[0].amount)
+		transfers = append(transfers, Transfer{
+			From:   losers[0].playerId,
+			To:     winners[0].playerId,
+			Amount: amount,
+		})
+		winners[0].amount -= amount
+		losers[0].amount -= amount
+		if winners[0].amount == 0 {
+			winners = winners[1:]
+		}
+		if losers[0].amount == 0 {
+			losers = losers[1:]
+		}
+	}
+	return transfers
+}
+
+func handleLedger(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	roomId := req.PathParams["roomId"]
+	roomLock.RLock()
+	r := roomMap[roomId]
+	roomLock.RUnlock()
+	if r == nil {
+		log.Printf("error: room %s does not exist", roomId)
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if status := r.opts.checkAccess(req.Request); status != 0 {
+		log.Printf("error: refused access to ledger of room %s", roomId)
+		rw.WriteHeader(status)
+		return
+	}
+	r.Lock()
+	// Chips in the pot belong to nobody yet, so only settle up between hands
+	if !r.betweenHands() {
+		r.Unlock()
+		rw.WriteHeader(http.StatusConflict)
+		return
+	}
+	report := r.ledgerReport()
+	r.Unlock()
+	rw.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(rw).Encode(report)
+	if err != nil {
+		log.Printf("error writing ledger for room %s: %s", roomId, err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+)
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []LedgerResult
+		want    []Transfer
+	}{
+		{
+			name:    "everyone even",
+			results: []LedgerResult{{PlayerId: "a"}, {PlayerId: "b"}},
+			want:    []Transfer{},
+		},
+		{
+			name:    "one loser pays everyone",
+			results: []LedgerResult{{PlayerId: "a", Net: 30}, {PlayerId: "b", Net: 20}, {PlayerId: "c", Net: -50}},
+			want:    []Transfer{{From: "c", To: "a", Amount: 30}, {From: "c", To: "b", Amount: 20}},
+		},
+		{
+			name:    "biggest loser pays biggest winner first",
+			results: []LedgerResult{{PlayerId: "a", Net: 50}, {PlayerId: "b", Net: -10}, {PlayerId: "c", Net: -40}},
+			want:    []Transfer{{From: "c", To: "a", Amount: 40}, {From: "b", To: "a", Amount: 10}},
+		},
+		{
+			name: "chained",
+			results: []LedgerResult{
+				{PlayerId: "a", Net: 70},
+				{PlayerId: "b", Net: 20},
+				{PlayerId: "c", Net: -60},
+				{PlayerId: "d", Net: -30},
+			},
+			want: []Transfer{
+				{From: "c", To: "a", Amount: 60},
+				{From: "d", To: "b", Amount: 20},
+				{From: "d", To: "a", Amount: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settle(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("settle() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLedgerReport(t *testing.T) {
+	r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+	// b raises, and takes the blinds when everyone folds
+	act(t, r, table.Action{Type: table.Raise, Chips: 10}, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	report := r.ledgerReport()
+	results := make(map[string]int)
+	for _, result := range report.Results {
+		results[result.PlayerId] = result.Net
+	}
+	if want := map[string]int{"a": -2, "b": 3, "c": -1}; !reflect.DeepEqual(results, want) {
+		t.Errorf("net results = %v; want %v", results, want)
+	}
+	want := []Transfer{{From: "a", To: "b", Amount: 2}, {From: "c", To: "b", Amount: 1}}
+	if !reflect.DeepEqual(report.Settlement, want) {
+		t.Errorf("settlement = %+v; want %+v", report.Settlement, want)
+	}
+}
@@ -0,0 +1,199 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// FIXED_LIMIT_CAP is the maximum number of bets and raises allowed in each
+// betting round of a fixed-limit game
+const FIXED_LIMIT_CAP = 4
+
+// raiseCounter counts the bets and raises made in the current betting round,
+// for enforcing the fixed-limit cap, and keeps the size of the biggest one,
+// which any further raise must at least match.
+type raiseCounter struct {
+	round table.Round
+	count int
+	size  int
+}
+
+func (opts *roomOpts) validateLimit() error {
+	fixedLimit := false
+	limits := []types.Limit{opts.Limit}
+	for _, game := range opts.Games {
+		limits = append(limits, game.Limit)
+	}
+	for _, limit := range limits {
+		if limit < types.LimitNoLimit || limit > types.LimitFixedLimit {
+			return fmt.Errorf("unknown betting limit %d", limit)
+		}
+		fixedLimit = fixedLimit || limit == types.LimitFixedLimit
+	}
+	if !fixedLimit {
+		return nil
+	}
+	if opts.SmallBet == 0 {
+		opts.SmallBet = opts.BigBlind
+	}
+	if opts.BigBet == 0 {
+		opts.BigBet = 2 * opts.SmallBet
+	}
+	if opts.SmallBet < 1 || opts.BigBet < opts.SmallBet {
+		return errors.New("fixed-limit bet sizes must be positive, and the big bet at least the small bet")
+	}
+	return nil
+}
+
+// tableLimit returns the table limit the engine should enforce. Fixed-limit
+// betting is enforced by the server on top of a no-limit table.
+func tableLimit(limit types.Limit) table.Limit {
+	if limit == types.LimitPotLimit {
+		return table.PotLimit
+	}
+	return table.NoLimit
+}
+
+// raiseLimits returns the range of chips the active player may bet or raise
+// by, on top of what they owe. A maximum of zero means they may not raise.
+func (r *room) raiseLimits(state table.State) *types.RaiseLimits {
+	if state.Status == table.Done || state.Active.ID == "" {
+		return nil
+	}
+	available := state.Active.Chips - state.Owed
+	if available <= 0 {
+		// Going all in is just calling
+		return &types.RaiseLimits{AllIn: true}
+	}
+	limits := &types.RaiseLimits{Min: r.minRaise(state.Round), Max: available}
+	limit := r.currentGame().Limit
+	switch limit {
+	case types.LimitPotLimit:
+		// The pot once the player has called
+		limits.Max = minInt(state.Pot+state.Owed, available)
+	case types.LimitFixedLimit:
+		if r.raisesThisRound(state.Round) >= FIXED_LIMIT_CAP {
+			return &types.RaiseLimits{}
+		}
+		bet := r.opts.SmallBet
+		if state.Round == table.Turn || state.Round == table.River {
+			bet = r.opts.BigBet
+		}
+		if bet > available {
+			// Too short to raise by a full bet, so can only go all in
+			return &types.RaiseLimits{AllIn: true}
+		}
+		limits.Min, limits.Max = bet, bet
+	}
+	if limits.Min > limits.Max {
+		limits.Min = limits.Max
+	}
+	limits.AllIn = limit == types.LimitNoLimit || available <= limits.Max
+	return limits
+}
+
+// checkRaiseLimits returns an error if the action would bet or raise by more
+// (or less) than the room's betting structure allows.
+func (r *room) checkRaiseLimits(action table.Action, state table.State) error {
+	limits := r.raiseLimits(state)
+	if limits == nil {
+		return nil
+	}
+	switch action.Type {
+	case table.Bet, table.Raise:
+		if limits.Max == 0 {
+			return errors.New("no more raises are allowed this round")
+		}
+		if action.Chips < limits.Min || action.Chips > limits.Max {
+			if limits.Min == limits.Max {
+				return fmt.Errorf("you can only raise by %d", limits.Max)
+			}
+			return fmt.Errorf("you can raise by between %d and %d", limits.Min, limits.Max)
+		}
+	case table.AllIn:
+		if !limits.AllIn {
+			return fmt.Errorf("going all in would raise by more than %d", limits.Max)
+		}
+	}
+	return nil
+}
+
+// minRaise returns the least a player may bet or raise by in the given
+// betting round: the size of the biggest bet or raise so far, or the big
+// blind if there hasn't been one.
+func (r *room) minRaise(round table.Round) int {
+	min := r.stakes().BigBlind
+	if r.raises.round == round && r.raises.size > min {
+		min = r.raises.size
+	}
+	return min
+}
+
+// raisesThisRound returns the number of bets and raises made so far in the
+// given betting round. The big blind counts as the first bet pre-flop.
+func (r *room) raisesThisRound(round table.Round) int {
+	if r.raises.round != round {
+		return 0
+	}
+	return r.raises.count
+}
+
+func (r *room) countRaise(action table.Action, state table.State) {
+	if r.raises.round != state.Round {
+		r.raises = raiseCounter{round: state.Round}
+	}
+	if isRaise(action, state) {
+		r.raises.count++
+		// A raise is the chips put in beyond what the player owed
+		size := state.Active.Chips - state.Owed
+		if action.Type == table.Straddle {
+			// A straddle is a bigger big blind, so raises have to match it
+			size = state.Owed + r.stakes().BigBlind
+		} else if action.Type != table.AllIn {
+			size = minInt(action.Chips, size)
+		}
+		if size > r.raises.size {
+			r.raises.size = size
+		}
+	}
+}
+
+// isRaise reports whether the action bets or raises, given the state before
+// it. Going all in for no more than a call doesn't count.
+func isRaise(action table.Action, state table.State) bool {
+	switch action.Type {
+	case table.Bet, table.Raise, table.Straddle:
+		return true
+	case table.AllIn:
+		return state.Active.Chips > state.Owed
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestRaiseLimits(t *testing.T) {
+	raise := func(chips int) table.Action {
+		return table.Action{Type: table.Raise, Chips: chips}
+	}
+	call := table.Action{Type: table.Call}
+	check := table.Action{Type: table.Check}
+	// b is under the gun, c is the small blind and a the big blind
+	tests := []struct {
+		name    string
+		limit   types.Limit
+		actions []table.Action
+		want    *types.RaiseLimits
+	}{
+		{
+			name: "no limit opening raise",
+			want: &types.RaiseLimits{Min: 2, Max: 98, AllIn: true},
+		},
+		{
+			name:    "no limit re-raise must match the raise",
+			actions: []table.Action{raise(10)},
+			want:    &types.RaiseLimits{Min: 10, Max: 88, AllIn: true},
+		},
+		{
+			name:    "no limit bigger re-raise",
+			actions: []table.Action{raise(10), raise(20)},
+			want:    &types.RaiseLimits{Min: 20, Max: 68, AllIn: true},
+		},
+		{
+			name:    "no limit bet after the flop",
+			actions: []table.Action{raise(10), call, call},
+			want:    &types.RaiseLimits{Min: 2, Max: 88, AllIn: true},
+		},
+		{
+			name:  "pot limit opening raise",
+			limit: types.LimitPotLimit,
+			want:  &types.RaiseLimits{Min: 2, Max: 5},
+		},
+		{
+			name:    "pot limit re-raise",
+			limit:   types.LimitPotLimit,
+			actions: []table.Action{raise(5)},
+			want:    &types.RaiseLimits{Min: 5, Max: 16},
+		},
+		{
+			name:  "fixed limit small bet",
+			limit: types.LimitFixedLimit,
+			want:  &types.RaiseLimits{Min: 2, Max: 2},
+		},
+		{
+			name:    "fixed limit big bet on the turn",
+			limit:   types.LimitFixedLimit,
+			actions: []table.Action{call, call, check, check, check, check},
+			want:    &types.RaiseLimits{Min: 4, Max: 4},
+		},
+		{
+			name:    "fixed limit cap",
+			limit:   types.LimitFixedLimit,
+			actions: []table.Action{raise(2), raise(2), raise(2)},
+			want:    &types.RaiseLimits{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{Limit: tt.limit}, "a", "b", "c")
+			act(t, r, tt.actions...)
+			got := r.raiseLimits(r.gameTable.State())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("raiseLimits() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
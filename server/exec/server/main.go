@@ -24,16 +24,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/alcamerone/joker/hand"
 	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/history"
 	"github.com/alcamerone/pocket2s/randSource"
+	"github.com/alcamerone/pocket2s/replay"
 	"github.com/alcamerone/pocket2s/types"
 	"github.com/gocraft/web"
 	"github.com/gorilla/websocket"
@@ -41,11 +43,18 @@ import (
 
 const (
 	MAX_PLAYERS         = 6
+	MIN_TABLE_SIZE      = 2
+	MAX_TABLE_SIZE      = 10
 	DEFAULT_BUY_IN      = 2000
 	DEFAULT_BIG_BLIND   = 20
 	DEFAULT_SMALL_BLIND = 10
 	DEFAULT_ANTE        = 0
+	HISTORY_MAX_HANDS   = 100
 	ENV_LOCAL           = "local"
+	ROOM_STORE_PATH_ENV = "ROOM_STORE_PATH"
+	SESSION_SECRET_ENV  = "SESSION_SECRET"
+	// PLAYER_WRITE_TIMEOUT bounds how long the room waits on a slow player
+	PLAYER_WRITE_TIMEOUT = time.Second
 )
 
 type playerMap struct {
@@ -54,36 +63,306 @@ type playerMap struct {
 }
 
 type room struct {
+	// The room must be locked while handling anything that reads or changes
+	// the game, since player messages, timers and requests each arrive on a
+	// goroutine of their own
+	sync.Mutex
 	id                   string
 	opts                 roomOpts
 	playerMap            playerMap
 	gameTable            *table.Table
 	cancelSelfDestructCh chan struct{}
+	actionTimer          actionTimer
+	handsPlayed          int
+	// restoredChips holds the chip counts of players restored from the room
+	// store, until they can be applied to a new table
+	restoredChips map[string]int
+	history       *history.Recorder
+	// randSrc is reseeded before every hand, and tableLog records every
+	// change made to gameTable, so that hands can be replayed
+	randSrc  *randSource.CryptoSource
+	tableLog *replay.Recorder
+	// handSeed is the seed the current hand was shuffled with. A seed gives
+	// away every card in the deck, so publicSeeds holds those of the hands
+	// on this table that have been revealed.
+	handSeed    []byte
+	publicSeeds map[string]bool
+	observers   observerSet
+	raises      raiseCounter
+	rotation    gameRotation
+	tournament  tournamentState
+	// coordinator is set if the room is one of the tables of a multi-table
+	// tournament
+	coordinator *coordinator
+	rebuys      rebuyState
+	ledger      ledger
+	// buyIns holds the amounts players have asked to buy in for, until they
+	// are dealt in
+	buyIns map[string]int
+	// leaving holds players who have asked to leave during a hand, and will
+	// be removed from the table once it is over
+	leaving map[string]bool
+	// standing holds players who have left the table but are still
+	// connected, so they can sit back down
+	standing map[string]*types.Player
+	// seatChanges holds requests to move seats, in the order they were made,
+	// and seatsChanged is set once players are no longer seated at the table
+	// in the order of their TablePos
+	seatChanges  []seatChange
+	seatsChanged bool
+	runout       runout
+	antes        anteState
+	showdown     showdownState
+	// straddles holds the players who have asked to straddle the next hand
+	straddles map[string]bool
 }
 
 type roomOpts struct {
-	BuyIn      int
+	// BuyIn is the default buy-in. If MinBuyIn and MaxBuyIn are set, players
+	// may choose to buy in for any amount between the two instead, and top up
+	// to MaxBuyIn between hands.
+	BuyIn    int
+	MinBuyIn int
+	MaxBuyIn int
+	// MaxPlayers is the number of seats at the table, defaulting to
+	// MAX_PLAYERS
+	MaxPlayers int
 	BigBlind   int
 	SmallBlind int
 	Ante       int
+	// AnteType is who posts the ante. A big-blind or button ante is paid by
+	// that player alone, for everyone dealt in, as dead money in the main pot.
+	AnteType types.AnteType
+	// Variant is the game played, either Texas Hold'em or Omaha
+	Variant table.Variant
+	// Games, if set, is a list of games the room rotates through instead of
+	// Variant and Limit, switching every RotateEvery hands, or every orbit of
+	// the table if RotateEvery is zero
+	Games       []types.Game
+	RotateEvery int
+	// Tournament, if set, makes the room a sit-and-go tournament. BuyIn is
+	// then the starting stack, and the blinds come from the tournament's
+	// levels rather than BigBlind, SmallBlind and Ante.
+	Tournament *tournamentOpts
+	// Rebuys, if set, limits how often broke players may buy back in, which
+	// is otherwise unlimited outside of tournaments and not allowed in them
+	Rebuys *rebuyOpts
+	// Limit is the betting structure. Fixed-limit rooms bet and raise by
+	// SmallBet before the turn and BigBet from the turn onwards, defaulting
+	// to the big blind and twice that respectively.
+	Limit    types.Limit
+	SmallBet int
+	BigBet   int
+	// RunItTwice offers to deal the rest of the board RunItTimes times,
+	// defaulting to twice, when players are all in before the river. Each pot
+	// is split evenly between the boards if everyone in the hand agrees.
+	RunItTwice bool
+	RunItTimes int
+	// Straddle lets the player under the gun ask to straddle for twice the
+	// big blind before the deal. Every BombPotEvery hands, if set, is instead
+	// a bomb pot: everyone puts BombPotAnte in the pot and play starts on the
+	// flop.
+	Straddle     bool
+	BombPotEvery int
+	BombPotAnte  int
+	// CommitReveal publishes a hash of each hand's shuffle seed before the
+	// deal, and the seed itself after the hand, so players can verify that
+	// the deck was not tampered with. The seed gives away the whole deck, so
+	// in this mode everyone can work out the cards that were mucked or folded
+	CommitReveal bool
+	// Password and InviteCode, if set, must be presented (either one will
+	// do) to join or watch the room
+	Password   string
+	InviteCode string
+	// ActionTimeout is the number of seconds the active player has to act
+	// before they are automatically checked or folded. Zero disables the timer.
+	ActionTimeout int
+	// TimeBank is the number of seconds each player may use once their
+	// ActionTimeout has run out. Every TimeBankTopUpHands hands, each player's
+	// bank is topped up by TimeBankTopUp seconds, up to a maximum of TimeBank.
+	TimeBank           int
+	TimeBankTopUp      int
+	TimeBankTopUpHands int
 }
 
 var (
-	router   *web.Router
-	roomMap  = make(map[string]*room)
-	roomLock = sync.RWMutex{}
+	router    *web.Router
+	roomMap   = make(map[string]*room)
+	roomLock  = sync.RWMutex{}
+	roomStore RoomStore
 )
 
+func newRoom(id string, opts roomOpts) *room {
+	return &room{
+		id: id,
+		playerMap: playerMap{
+			players: make(map[string]*types.Player, opts.maxPlayers()),
+		},
+		cancelSelfDestructCh: make(chan struct{}),
+		opts:                 opts,
+		history:              history.NewRecorder(id, HISTORY_MAX_HANDS),
+		tableLog:             replay.NewRecorder(),
+		buyIns:               make(map[string]int),
+		leaving:              make(map[string]bool),
+		standing:             make(map[string]*types.Player),
+		straddles:            make(map[string]bool),
+		rebuys:               newRebuyState(),
+		observers: observerSet{
+			conns: make(map[*websocket.Conn]struct{}),
+		},
+	}
+}
+
+func (r *room) tableOptions() table.Options {
+	return table.Options{
+		Buyin:   r.opts.BuyIn,
+		Variant: r.currentGame().Variant,
+		Stakes:  r.stakes(),
+		Limit:   tableLimit(r.currentGame().Limit),
+		OneShot: true,
+	}
+}
+
+func (opts *roomOpts) validate() error {
+	if opts.Variant != table.TexasHoldem && opts.Variant != table.OmahaHi {
+		return fmt.Errorf("unknown variant %d", opts.Variant)
+	}
+	err := opts.validateGames()
+	if err != nil {
+		return err
+	}
+	if opts.Tournament != nil {
+		err = opts.Tournament.validate()
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Rebuys != nil {
+		err = opts.Rebuys.validate()
+		if err != nil {
+			return err
+		}
+	}
+	err = opts.validateBuyIn()
+	if err != nil {
+		return err
+	}
+	err = opts.validateRunItTwice()
+	if err != nil {
+		return err
+	}
+	err = opts.validateForcedBets()
+	if err != nil {
+		return err
+	}
+	err = opts.validateAnteType()
+	if err != nil {
+		return err
+	}
+	if opts.MaxPlayers != 0 &&
+		(opts.MaxPlayers < MIN_TABLE_SIZE || opts.MaxPlayers > MAX_TABLE_SIZE) {
+		return fmt.Errorf(
+			"tables must seat between %d and %d players",
+			MIN_TABLE_SIZE,
+			MAX_TABLE_SIZE)
+	}
+	return opts.validateLimit()
+}
+
 func (r *room) getPlayerIds() []string {
 	r.playerMap.RLock()
 	defer r.playerMap.RUnlock()
-	playerIds := make([]string, len(r.playerMap.players))
+	players := make([]*types.Player, 0, len(r.playerMap.players))
 	for _, player := range r.playerMap.players {
-		playerIds[player.TablePos] = player.Id
+		players = append(players, player)
+	}
+	// Players can leave the table, so seats are not necessarily contiguous
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].TablePos < players[j].TablePos
+	})
+	playerIds := make([]string, len(players))
+	for i, player := range players {
+		playerIds[i] = player.Id
 	}
 	return playerIds
 }
 
+// freeTablePos returns the lowest seat nobody is sitting in. The caller must
+// hold the player map lock.
+func (r *room) freeTablePos() int {
+	taken := make(map[int]bool, len(r.playerMap.players))
+	for _, player := range r.playerMap.players {
+		taken[player.TablePos] = true
+	}
+	tablePos := 0
+	for taken[tablePos] {
+		tablePos++
+	}
+	return tablePos
+}
+
+// seatPlayer moves a player who is already connected into the room with the
+// given stack. They sit out until they say they are ready.
+func (r *room) seatPlayer(player *types.Player, chips int) {
+	r.playerMap.Lock()
+	player.TablePos = r.freeTablePos()
+	player.RoomId = r.id
+	player.Ready = false
+	player.SittingOut = true
+	r.playerMap.players[player.Id] = player
+	r.playerMap.Unlock()
+
+	if r.gameTable == nil {
+		if r.restoredChips == nil {
+			r.restoredChips = make(map[string]int)
+		}
+		r.restoredChips[player.Id] = chips
+		return
+	}
+	r.gameTable.AddPlayer(player.Id, true)
+	r.tableLog.Record(replay.Event{
+		Type:       replay.EventAddPlayer,
+		PlayerId:   player.Id,
+		Defaulting: true,
+	})
+	r.checkSeating()
+	r.setChips(player.Id, chips)
+}
+
+// removePlayer takes a player out of the room without closing their
+// connection, and returns the chips they had left. It must only be called
+// between hands.
+func (r *room) removePlayer(playerId string) int {
+	r.playerMap.Lock()
+	delete(r.playerMap.players, playerId)
+	r.playerMap.Unlock()
+
+	delete(r.buyIns, playerId)
+	delete(r.leaving, playerId)
+	if r.gameTable == nil {
+		// Players who haven't been dealt in yet haven't bought in, unless
+		// they were restored or moved here with a stack
+		chips, ok := r.restoredChips[playerId]
+		if ok {
+			delete(r.restoredChips, playerId)
+			r.recordLedger(LedgerEntryCashOut, playerId, chips)
+		}
+		return chips
+	}
+	chips := getPlayerState(playerId, r.gameTable).Chips
+	r.recordLedger(LedgerEntryCashOut, playerId, chips)
+	err := r.gameTable.RemovePlayer(playerId)
+	if err != nil {
+		log.Printf("error removing %s from the table in room %s: %s", playerId, r.id, err.Error())
+		return chips
+	}
+	r.tableLog.Record(replay.Event{
+		Type:     replay.EventRemovePlayer,
+		PlayerId: playerId,
+	})
+	return chips
+}
+
 var wsUpgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -98,25 +377,33 @@ func init() {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 
 	// TODO default room for dev. Remove before prod
-	roomMap["pocket2s"] = &room{
-		id: "pocket2s",
-		playerMap: playerMap{
-			players: make(map[string]*types.Player, MAX_PLAYERS),
-		},
-		cancelSelfDestructCh: make(chan struct{}),
-		opts: roomOpts{
-			BuyIn:      DEFAULT_BUY_IN,
-			BigBlind:   DEFAULT_BIG_BLIND,
-			SmallBlind: DEFAULT_SMALL_BLIND,
-			Ante:       DEFAULT_ANTE,
-		},
+	roomMap["pocket2s"] = newRoom("pocket2s", roomOpts{
+		BuyIn:      DEFAULT_BUY_IN,
+		BigBlind:   DEFAULT_BIG_BLIND,
+		SmallBlind: DEFAULT_SMALL_BLIND,
+		Ante:       DEFAULT_ANTE,
+	})
+
+	initSessionSecret()
+	if storePath := os.Getenv(ROOM_STORE_PATH_ENV); storePath != "" {
+		roomStore = newFileRoomStore(storePath)
+	} else {
+		roomStore = newMemoryRoomStore()
 	}
+	restoreRooms()
+
 	router = web.New(Context{})
 	router.Subrouter(Context{}, "").
 		Middleware(setHeaders).
 		Get("/check/:roomId", handleRoomCheck).
 		Post("/create/:roomId", handleCreateRoom).
-		Get("/connect/:roomId/:playerId", handleConnect)
+		Get("/connect/:roomId/:playerId", handleConnect).
+		Get("/history/:roomId", handleHistory).
+		Get("/watch/:roomId", handleWatch).
+		Get("/ledger/:roomId", handleLedger).
+		Post("/tournament/create/:tournamentId", handleCreateTournament).
+		Post("/tournament/start/:tournamentId", handleStartTournament).
+		Get("/tournament/connect/:tournamentId/:playerId", handleTournamentConnect)
 
 	router.Subrouter(Context{}, "/healthcheck").
 		Get("/", handleHealthcheck)
@@ -195,7 +482,14 @@ func handleRoomCheck(ctx *Context, rw web.ResponseWriter, req *web.Request) {
 	roomLock.RLock()
 	defer roomLock.RUnlock()
 	if room := roomMap[roomId]; room != nil {
+		rw.Header().Set("Content-Type", "application/json")
 		rw.WriteHeader(http.StatusConflict)
+		err := json.NewEncoder(rw).Encode(roomCheckResponse{
+			Protected: room.opts.isProtected(),
+		})
+		if err != nil {
+			log.Printf("error writing room check response: %s", err.Error())
+		}
 		return
 	}
 	rw.WriteHeader(http.StatusOK)
@@ -225,21 +519,77 @@ func handleCreateRoom(ctx *Context, rw web.ResponseWriter, req *web.Request) {
 		rw.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	err = opts.validate()
+	if err != nil {
+		log.Printf("Invalid options for room %s: %s", roomId, err.Error())
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
 	roomLock.Lock()
 	defer roomLock.Unlock()
-	roomMap[roomId] = &room{
-		id: roomId,
-		playerMap: playerMap{
-			players: make(map[string]*types.Player, MAX_PLAYERS),
-		},
-		cancelSelfDestructCh: make(chan struct{}),
-		opts:                 opts,
-	}
+	roomMap[roomId] = newRoom(roomId, opts)
+	roomMap[roomId].persist()
 	log.Printf("created room %s", roomId)
 	rw.WriteHeader(http.StatusCreated)
 }
 
+// handleHistory returns the room's recent hands in the PokerStars text
+// format, or as JSON if the "format" query parameter is "json". If it is
+// "replay", the replay log for the room's current table is returned instead,
+// without the seeds of hands in which anyone's cards stayed hidden.
+func handleHistory(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	roomId := req.PathParams["roomId"]
+	roomLock.RLock()
+	r := roomMap[roomId]
+	roomLock.RUnlock()
+	if r == nil {
+		log.Printf("error: room %s does not exist", roomId)
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if status := r.opts.checkAccess(req.Request); status != 0 {
+		log.Printf("error: refused access to history of room %s", roomId)
+		rw.WriteHeader(status)
+		return
+	}
+	format := req.URL.Query().Get("format")
+	r.Lock()
+	hands := r.history.Hands()
+	// A hand waiting on whether to run it twice has been recorded, but with
+	// everyone's cards and a single board
+	if r.runout.pending && len(hands) > 0 {
+		hands = hands[:len(hands)-1]
+	}
+	// The log includes the seed for the hand in progress, so only give it out
+	// between hands
+	betweenHands := r.betweenHands()
+	var tableLog replay.Log
+	if format == "replay" && betweenHands {
+		tableLog = r.publicTableLog()
+	}
+	r.Unlock()
+	var err error
+	switch format {
+	case "json":
+		rw.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(rw).Encode(hands)
+	case "replay":
+		if !betweenHands {
+			rw.WriteHeader(http.StatusConflict)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(rw).Encode(tableLog)
+	default:
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err = history.WritePokerStars(rw, hands)
+	}
+	if err != nil {
+		log.Printf("error writing history for room %s: %s", roomId, err.Error())
+	}
+}
+
 func handleConnect(ctx *Context, rw web.ResponseWriter, req *web.Request) {
 	roomId := req.PathParams["roomId"]
 	var r *room
@@ -250,51 +600,111 @@ func handleConnect(ctx *Context, rw web.ResponseWriter, req *web.Request) {
 		rw.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if r.coordinator != nil {
+		log.Printf("error: room %s is a table in tournament %s, which must be joined instead", roomId, r.coordinator.id)
+		rw.WriteHeader(http.StatusLocked)
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
 	r.cancelSelfDestruct()
 
 	playerId := req.PathParams["playerId"]
 
 	r.playerMap.Lock()
-	tableFull := len(r.playerMap.players) > MAX_PLAYERS
+	tableFull := len(r.playerMap.players) >= r.opts.maxPlayers()
 	existingPlayer, playerExists := r.playerMap.players[playerId]
-	if playerExists && existingPlayer.Conn != nil {
+	if _, standing := r.standing[playerId]; standing || playerExists && existingPlayer.Conn != nil {
 		log.Printf("error: a player named %s is already at the table", playerId)
 		rw.WriteHeader(http.StatusConflict)
+		r.playerMap.Unlock()
+		return
+	}
+	// Players restored from a store saved before session tokens were
+	// introduced have no nonce, so let the first person to return claim them
+	if playerExists && existingPlayer.SessionNonce != "" &&
+		!validSessionToken(roomId, existingPlayer, req.URL.Query().Get("token")) {
+		log.Printf("error: invalid session token for %s in room %s", playerId, roomId)
+		rw.WriteHeader(http.StatusUnauthorized)
+		r.playerMap.Unlock()
 		return
 	}
-	if tableFull {
+	// Returning players have already proven they may join
+	if !playerExists || existingPlayer.SessionNonce == "" {
+		if status := r.opts.checkAccess(req.Request); status != 0 {
+			log.Printf("error: %s gave the wrong password or invite code for room %s", playerId, roomId)
+			rw.WriteHeader(status)
+			r.playerMap.Unlock()
+			return
+		}
+	}
+	if !playerExists && r.isTournament() && r.tournament.started {
+		log.Printf("error: the tournament in room %s has already started", roomId)
+		rw.WriteHeader(http.StatusLocked)
+		r.playerMap.Unlock()
+		return
+	}
+	if !playerExists && tableFull {
 		log.Println("error: the table already has the maximum number of players")
 		rw.WriteHeader(http.StatusLocked)
+		r.playerMap.Unlock()
 		return
 	}
+	tablePos := -1
+	if seat := req.URL.Query().Get("seat"); seat != "" && !playerExists {
+		var status int
+		tablePos, status = r.requestedTablePos(seat)
+		if status != 0 {
+			log.Printf("error: %s cannot sit in seat %s in room %s", playerId, seat, roomId)
+			rw.WriteHeader(status)
+			r.playerMap.Unlock()
+			return
+		}
+	} else if !playerExists {
+		tablePos = r.freeTablePos()
+	}
 
 	conn, err := wsUpgrader.Upgrade(rw, req.Request, nil)
 	if err != nil {
 		log.Printf("error establishing connection: %s", err.Error())
 		rw.WriteHeader(http.StatusInternalServerError)
+		r.playerMap.Unlock()
 		return
 	}
 
+	var token string
 	if playerExists {
-		existingPlayer.Conn = conn
-		existingPlayer.Ready = false
-		existingPlayer.SittingOut = true
+		r.rejoin(existingPlayer, conn)
+		if existingPlayer.SessionNonce == "" {
+			token, err = newSessionToken(roomId, existingPlayer)
+		} else {
+			token = req.URL.Query().Get("token")
+		}
 		log.Printf("%s has rejoined", playerId)
 	} else {
-		tablePos := len(r.playerMap.players)
 		r.playerMap.players[playerId] = &types.Player{
 			Id:       playerId,
 			TablePos: tablePos,
 			Conn:     conn,
+			TimeBank: r.opts.TimeBank,
+			RoomId:   roomId,
 		}
+		token, err = newSessionToken(roomId, r.playerMap.players[playerId])
 		log.Printf("%s has joined", playerId)
 	}
-	err = conn.WriteJSON(types.ToPlayerMessage{Type: types.MessageTypeHello})
+	if err != nil {
+		log.Printf("error issuing session token to %s: %s", playerId, err.Error())
+	}
+	err = conn.WriteJSON(types.ToPlayerMessage{
+		Type:         types.MessageTypeHello,
+		SessionToken: token,
+	})
 	if err != nil {
 		// TODO handle
 		log.Printf("error sending \"hello\" message to player: %s", err.Error())
 	}
 	r.playerMap.Unlock()
+	r.persist()
 	r.broadcast(types.ToPlayerMessage{
 		Type:     types.MessageTypePlayerConnected,
 		PlayerId: playerId,
@@ -310,16 +720,35 @@ func listenForPlayerMessages(player *types.Player, r *room) {
 	)
 	for {
 		err = player.Conn.ReadJSON(&msg)
+		// Players in a multi-table tournament can be moved to another room
+		// while connected, and have no room at all until it starts
+		if r == nil || r.id != player.RoomId {
+			roomLock.RLock()
+			r = roomMap[player.RoomId]
+			roomLock.RUnlock()
+		}
 		if err != nil {
 			if isClosedConnectionError(err.Error()) {
-				r.handlePlayerError(player, err)
+				if r != nil {
+					r.Lock()
+					r.handlePlayerError(player, err)
+					r.Unlock()
+				} else {
+					log.Printf("connection to %s closed with %s", player.Id, err.Error())
+				}
 				player.Conn = nil
 				break
 			}
 			log.Printf("error receiving message from %s: %s", player.Id, err.Error())
 			continue
 		}
+		if r == nil {
+			log.Printf("ignoring message from %s, who is not seated at a table", player.Id)
+			continue
+		}
+		r.Lock()
 		r.handleMessageFromPlayer(msg, player)
+		r.Unlock()
 	}
 }
 
@@ -331,9 +760,20 @@ func (r *room) handleMessageFromPlayer(
 		state table.State
 		err   error
 	)
+	if _, standing := r.standing[player.Id]; standing && !r.sitBackDown(msg, player) {
+		return
+	}
 	switch msg.Type {
 	case types.MessageTypeReady, types.MessageTypeSitOut:
 		isReady := msg.Type == types.MessageTypeReady
+		if isReady && !r.isDealtIn(player.Id) {
+			amount, err := r.buyInAmount(msg.Amount)
+			if err != nil {
+				r.sendError(player, types.ErrorCodeBuyInOutOfRange, err.Error())
+				return
+			}
+			r.buyIns[player.Id] = amount
+		}
 		player.Ready = isReady
 		player.SittingOut = !isReady
 		if r.gameTable != nil {
@@ -341,8 +781,24 @@ func (r *room) handleMessageFromPlayer(
 			if pState.ID == player.Id {
 				// Player already seated at table
 				r.gameTable.SetPlayerDefaulting(player.Id, !isReady)
+				r.tableLog.Record(replay.Event{
+					Type:       replay.EventSetDefaulting,
+					PlayerId:   player.Id,
+					Defaulting: !isReady,
+				})
 			} else {
 				r.gameTable.AddPlayer(player.Id, !isReady)
+				r.tableLog.Record(replay.Event{
+					Type:       replay.EventAddPlayer,
+					PlayerId:   player.Id,
+					Defaulting: !isReady,
+				})
+				r.checkSeating()
+				amount := r.takeBuyIn(player.Id)
+				if amount != r.opts.BuyIn {
+					r.setChips(player.Id, amount)
+				}
+				r.recordLedger(LedgerEntryBuyIn, player.Id, amount)
 			}
 		}
 		if isReady {
@@ -350,72 +806,238 @@ func (r *room) handleMessageFromPlayer(
 		} else {
 			log.Printf("%s is sitting out", player.Id)
 		}
-		if (r.gameTable == nil || r.gameTable.State().Status == table.Done) &&
-			r.playersAreReady() {
+		if r.betweenHands() && r.playersAreReady() {
+			if r.isTournament() && r.tournament.finished {
+				r.sendError(
+					player,
+					types.ErrorCodeTournamentOver,
+					"The tournament is over.")
+				return
+			}
 			// START THE GAME ALREADY
-			if r.gameTable == nil {
-				dealer := hand.NewDealer(
-					rand.New(
-						randSource.NewConcurrencySafeSource(
-							time.Now().UnixNano(),
-						),
-					),
-				)
-				r.gameTable = table.New(
-					dealer,
-					table.Options{
-						Buyin:   r.opts.BuyIn,
-						Variant: table.TexasHoldem,
-						Stakes: table.Stakes{
-							BigBlind:   r.opts.BigBlind,
-							SmallBlind: r.opts.SmallBlind,
-							Ante:       r.opts.Ante,
-						},
-						Limit:   table.NoLimit,
-						OneShot: true,
-					},
-					r.getPlayerIds(),
-					r.getPlayersSittingOut())
-				state = r.gameTable.State()
-			} else {
-				state = r.gameTable.NewRound()
+			var ok bool
+			state, ok = r.startHand(player)
+			if !ok {
+				return
 			}
 		} else {
 			return
 		}
 	case types.MessageTypeBuyIn:
-		if r.gameTable != nil {
-			err = r.gameTable.BuyPlayerIn(player.Id)
+		if r.isTournament() && r.opts.Rebuys == nil {
+			r.sendError(
+				player,
+				types.ErrorCodeRebuyNotAllowed,
+				"Sorry, there are no rebuys in this tournament.")
+			return
+		}
+		if r.gameTable == nil {
+			// Players buy in when the first hand is dealt
+			r.sendError(
+				player,
+				types.ErrorCodeBuyInFailed,
+				"Sorry, you can only buy back in once the game has started.")
+			return
+		}
+		if r.opts.Rebuys != nil {
+			if !r.rebuy(player, msg.Amount) {
+				return
+			}
+		} else {
+			if !r.canBuyBackIn(player) {
+				return
+			}
+			amount, err := r.buyInAmount(msg.Amount)
+			if err != nil {
+				r.sendError(player, types.ErrorCodeBuyInOutOfRange, err.Error())
+				return
+			}
+			added, err := r.buyBackIn(player.Id, amount)
 			if err != nil {
 				log.Printf("error buying %s in; not found", player.Id)
+				r.sendError(
+					player,
+					types.ErrorCodeBuyInFailed,
+					"Sorry, we couldn't buy you in.")
+				return
 			}
-			player.Broke = false
-			r.handleMessageFromPlayer(
-				types.FromPlayerMessage{Type: types.MessageTypeReady},
-				player)
-			return
+			r.recordLedger(LedgerEntryRebuy, player.Id, added)
 		}
+		player.Broke = false
+		r.handleMessageFromPlayer(
+			types.FromPlayerMessage{Type: types.MessageTypeReady},
+			player)
+		return
+	case types.MessageTypeAddOn:
+		r.addOn(player)
+		return
+	case types.MessageTypeTopUp:
+		r.topUp(player, msg.Amount)
+		return
+	case types.MessageTypeLeave:
+		r.leave(player)
+		return
+	case types.MessageTypeSeatChange:
+		r.requestSeatChange(player, msg.Seat)
+		return
 	case types.MessageTypePlayerAction:
 		state, err = r.handleActionByPlayer(msg.Action, player)
 		if err != nil {
 			log.Println(err.Error())
 			return
 		}
+		if r.runout.pending {
+			// The result is held back until everyone has decided whether
+			// to run it twice
+			return
+		}
+	case types.MessageTypeRunItTwice:
+		r.answerRunItTwice(player, msg.Agree)
+		return
+	case types.MessageTypeStraddle:
+		r.requestStraddle(player)
+		return
+	case types.MessageTypeShowCards:
+		r.showCards(player, msg.Cards)
+		return
 	default:
 		log.Printf("invalid message type %d", msg.Type)
+		r.sendError(
+			player,
+			types.ErrorCodeUnknownMessageType,
+			fmt.Sprintf("The server doesn't understand messages of type %d.", msg.Type))
 		return
 	}
-	tableState := obfuscateTableState(state)
-	result := getResult(state)
-	r.broadcast(types.ToPlayerMessage{
-		Type:       types.MessageTypeTableState,
-		TableState: tableState,
-		Result:     result,
-	})
+	r.broadcastState(state)
+}
+
+// broadcastState sends everyone the state of the table after a change, and
+// wraps up the hand if it is over.
+func (r *room) broadcastState(state table.State) {
+	pots := r.potResults(state)
+	result := resultString(pots, r.runout.boards, state.Result.Returned)
 	if result != "" {
+		r.revealShowdown(state)
+	}
+	tableState := obfuscateTableState(r.withAntes(state), r.showdown.shown)
+	deadline := r.startActionTimer(state)
+	game := r.currentGame()
+	toPlayerMsg := types.ToPlayerMessage{
+		Type:           types.MessageTypeTableState,
+		TableState:     tableState,
+		Result:         result,
+		ActionDeadline: deadline,
+		RaiseLimits:    r.raiseLimits(state),
+		Game:           &game,
+		BombPot:        r.isBombPot(),
+		Antes:          r.antes.posted,
+		Showdown:       r.showdownResult(),
+		Pots:           pots,
+	}
+	if result != "" {
+		toPlayerMsg.Returned = state.Result.Returned
+	}
+	if result != "" && len(r.runout.boards) > 1 {
+		toPlayerMsg.Boards = r.runout.boards
+	}
+	if result != "" {
+		toPlayerMsg.ShuffleSeed = r.revealSeed(state)
+	}
+	if result != "" {
+		r.endRebuyHand()
+		if r.rebuys.addOnOpen {
+			toPlayerMsg.AddOn = r.opts.Rebuys.AddOn
+		}
+	}
+	r.broadcast(toPlayerMsg)
+	if result != "" {
+		r.handsPlayed++
+		r.advanceGame(state)
+		r.endTournamentHand(state)
+		r.removeLeavers()
+		r.processSeatChanges()
+		r.topUpTimeBanks()
 		r.resetPlayersReady()
+		r.persist()
 	}
-	return
+}
+
+// startHand deals a new hand, creating the table first if necessary. It
+// returns false if the hand could not be dealt.
+func (r *room) startHand(player *types.Player) (table.State, bool) {
+	var state table.State
+	// Every hand is shuffled with a fresh seed, which is recorded so that
+	// the hand can be replayed later
+	seed, err := randSource.NewSeed()
+	if err != nil {
+		log.Printf("error generating shuffle seed: %s", err.Error())
+		r.sendError(
+			player,
+			types.ErrorCodeInternal,
+			"Something went wrong shuffling the deck. Please try again.")
+		return state, false
+	}
+	r.handSeed = seed
+	if r.opts.CommitReveal {
+		r.broadcast(types.ToPlayerMessage{
+			Type:              types.MessageTypeShuffleCommitment,
+			ShuffleCommitment: randSource.Commitment(seed),
+		})
+	}
+	if r.gameTable == nil {
+		err = r.newTable(seed, r.getPlayerIds(), r.getPlayersSittingOut())
+		if err != nil {
+			log.Printf("error creating table: %s", err.Error())
+			return state, false
+		}
+		// Restored players bought in before the server restarted
+		for _, playerId := range r.getPlayerIds() {
+			if _, restored := r.restoredChips[playerId]; restored {
+				continue
+			}
+			amount := r.takeBuyIn(playerId)
+			if amount != r.opts.BuyIn {
+				if r.restoredChips == nil {
+					r.restoredChips = make(map[string]int)
+				}
+				r.restoredChips[playerId] = amount
+			}
+			r.recordLedger(LedgerEntryBuyIn, playerId, amount)
+		}
+		r.applyRestoredChips()
+		state = r.gameTable.State()
+	} else if r.gameChanged() || r.seatsChanged {
+		err = r.rebuildTable(seed)
+		if err != nil {
+			log.Printf("error rebuilding table: %s", err.Error())
+			return state, false
+		}
+		state = r.gameTable.State()
+	} else {
+		err = r.randSrc.SetSeed(seed)
+		if err != nil {
+			log.Printf("error reseeding shuffle source: %s", err.Error())
+			return state, false
+		}
+		state = r.gameTable.NewRound()
+		r.tableLog.Record(replay.Event{
+			Type: replay.EventNewRound,
+			Seed: seed,
+		})
+	}
+	r.noteAntes(state)
+	r.runout.boards = nil
+	r.runout.pots = nil
+	r.showdown = showdownState{}
+	r.history.StartHand(r.withAntes(state), seed)
+	r.history.RecordAntes(r.antes.posted)
+	r.history.RecordLimit(r.currentGame().Limit, r.opts.SmallBet, r.opts.BigBet)
+	r.startTournamentHand(state)
+	r.startRebuyHand()
+	// The big blind counts as the first bet
+	r.raises = raiseCounter{round: table.PreFlop, count: 1}
+	state = r.startForcedBets(state)
+	return state, true
 }
 
 func (r *room) playersAreReady() bool {
@@ -460,30 +1082,80 @@ func (r *room) getPlayersSittingOut() []string {
 }
 
 func (r *room) handleActionByPlayer(action table.Action, player *types.Player) (table.State, error) {
+	if r.gameTable == nil || r.gameTable.State().Status == table.Done {
+		r.sendError(
+			player,
+			types.ErrorCodeNoGameInProgress,
+			"There is no hand in progress.")
+		return table.State{}, fmt.Errorf(
+			"ignoring action request %s from player %s as there is no hand in progress",
+			action.Type.String(),
+			player.Id)
+	}
 	if player.Id != r.gameTable.Active().ID {
+		r.sendError(
+			player,
+			types.ErrorCodeNotYourTurn,
+			fmt.Sprintf("It's not your turn; it's %s's.", r.gameTable.Active().ID))
 		return table.State{}, fmt.Errorf(
 			"ignoring action request %s from player %s as it is not their turn",
 			action.Type.String(),
 			player.Id)
 	}
+	if action.Type == table.Straddle {
+		r.sendError(player, types.ErrorCodeStraddleNotAllowed, "Sorry, you can only straddle before the deal.")
+		return table.State{}, fmt.Errorf("ignoring straddle from player %s during the hand", player.Id)
+	}
+	before := r.gameTable.State()
+	err := r.checkRaiseLimits(action, before)
+	if err != nil {
+		r.sendError(player, types.ErrorCodeBetOutOfRange, "Sorry, "+err.Error()+".")
+		r.sendIllegalAction(player)
+		return table.State{}, fmt.Errorf("%s by player %s", err.Error(), player.Id)
+	}
 	state, err := r.gameTable.Act(action)
 	if err != nil {
-		// TODO handle error
-		player.Conn.WriteJSON(types.ToPlayerMessage{
-			Type:        types.MessageTypeIllegalAction,
-			TableState:  obfuscateTableState(r.gameTable.State()),
-			PlayerState: getPlayerState(player.Id, r.gameTable),
-		})
+		r.sendIllegalAction(player)
 		return table.State{}, fmt.Errorf("%s by player %s", err.Error(), player.Id)
 	}
+	r.countRaise(action, before)
+	r.noteAction(player.Id, action, before)
+	r.history.RecordAction(player.Id, action, before, state)
+	r.tableLog.Record(replay.Event{
+		Type:     replay.EventAction,
+		PlayerId: player.Id,
+		Action:   action,
+	})
 	r.broadcast(types.ToPlayerMessage{
 		Type:         types.MessageTypePlayerAction,
 		PlayerAction: types.PlayerAction{Action: action, PlayerId: player.Id},
 	})
+	r.offerRunItTwice(before, state)
 	return state, err
 }
 
-func obfuscateTableState(tableState table.State) table.State {
+func (r *room) sendIllegalAction(player *types.Player) {
+	if player.Conn == nil {
+		return
+	}
+	// TODO handle error
+	pState := getPlayerState(player.Id, r.gameTable)
+	pState.ChipsInPot = r.chipsInPot(pState)
+	game := r.currentGame()
+	player.Conn.WriteJSON(types.ToPlayerMessage{
+		Type:           types.MessageTypeIllegalAction,
+		TableState:     obfuscateTableState(r.withAntes(r.gameTable.State()), r.showdown.shown),
+		PlayerState:    pState,
+		ActionDeadline: r.actionTimer.getDeadline(),
+		RaiseLimits:    r.raiseLimits(r.gameTable.State()),
+		Game:           &game,
+		Antes:          r.antes.posted,
+	})
+}
+
+// obfuscateTableState hides every player's cards from the table state, apart
+// from those shown once the hand is over.
+func obfuscateTableState(tableState table.State, shown map[string][]hand.Card) table.State {
 	seats := make([]table.Player, len(tableState.Seats))
 	for i, player := range tableState.Seats {
 		seats[i] = table.Player{
@@ -497,11 +1169,11 @@ func obfuscateTableState(tableState table.State) table.State {
 			// Send an empty array to signal to the front-end
 			// that this player has no cards
 			seats[i].Cards = make([]hand.Card, 0)
-		} else if tableState.Status == table.Done &&
-			len(tableState.Result.Contestants) > 1 &&
-			playerIsContesting(player.ID, tableState) {
-			seats[i].Cards = player.Cards
+		} else if tableState.Status == table.Done {
+			seats[i].Cards = shown[player.ID]
 		}
+		// Otherwise Cards is left nil, and the front-end draws as many
+		// face-down cards as the variant deals
 	}
 	tableState.Seats = seats
 	active := table.Player{
@@ -510,9 +1182,29 @@ func obfuscateTableState(tableState table.State) table.State {
 		ChipsInPot: tableState.Active.ChipsInPot,
 	}
 	tableState.Active = active
+	tableState.Dealer = table.Player{ID: tableState.Dealer.ID}
+	tableState.SmallBlind = table.Player{ID: tableState.SmallBlind.ID}
+	tableState.BigBlind = table.Player{ID: tableState.BigBlind.ID}
+	tableState.Result.Winners = obfuscatePlayers(tableState.Result.Winners, shown)
+	tableState.Result.Contestants = obfuscatePlayers(tableState.Result.Contestants, shown)
 	return tableState
 }
 
+func obfuscatePlayers(players []table.Player, shown map[string][]hand.Card) []table.Player {
+	if players == nil {
+		return nil
+	}
+	obfuscated := make([]table.Player, len(players))
+	for i, player := range players {
+		obfuscated[i] = table.Player{
+			ID:    player.ID,
+			Chips: player.Chips,
+			Cards: shown[player.ID],
+		}
+	}
+	return obfuscated
+}
+
 func playerIsContesting(playerId string, tableState table.State) bool {
 	for _, contestant := range tableState.Result.Contestants {
 		if contestant.ID == playerId {
@@ -523,27 +1215,44 @@ func playerIsContesting(playerId string, tableState table.State) bool {
 }
 
 func (r *room) broadcast(msg types.ToPlayerMessage) {
-	var err error
 	r.playerMap.RLock()
-	defer r.playerMap.RUnlock()
+	failed := make(map[*types.Player]error)
 	for _, player := range r.playerMap.players {
 		if msg.Type == types.MessageTypeTableState {
 			msg.PlayerState = getPlayerState(player.Id, r.gameTable)
+			msg.PlayerState.ChipsInPot = r.chipsInPot(msg.PlayerState)
+			msg.TimeBank = player.TimeBank
 			if msg.PlayerState.Chips == 0 && r.gameTable.State().Status == table.Done {
 				player.Broke = true
 			}
 		}
 		if player.Conn != nil {
-			err = retrySend(player, msg)
+			err := sendToPlayer(player, msg)
 			if err != nil {
-				log.Printf(
-					"giving up sending state to player %s in room %s due to too many errors",
-					player.Id,
-					r.id)
-				r.handlePlayerError(player, err)
+				failed[player] = err
 			}
 		}
 	}
+	r.playerMap.RUnlock()
+	// Handling an error broadcasts the disconnection, and may act for the
+	// player, so it has to wait until the player map is unlocked
+	for player, err := range failed {
+		r.handlePlayerError(player, err)
+	}
+	r.broadcastToObservers(msg)
+}
+
+func (r *room) sendError(player *types.Player, code types.ErrorCode, text string) {
+	if player.Conn == nil {
+		return
+	}
+	err := sendToPlayer(player, types.ToPlayerMessage{
+		Type:  types.MessageTypeError,
+		Error: &types.Error{Code: code, Message: text},
+	})
+	if err != nil {
+		log.Printf("error sending error message to %s: %s", player.Id, err.Error())
+	}
 }
 
 func getPlayerState(playerId string, t *table.Table) table.Player {
@@ -556,35 +1265,52 @@ func getPlayerState(playerId string, t *table.Table) table.Player {
 	return table.Player{}
 }
 
-func retrySend(player *types.Player, msg types.ToPlayerMessage) error {
-	var (
-		backoff time.Duration
-		err     error
-	)
-	backoff = 100 * time.Millisecond
-	for i := 0; i < 5; i++ {
-		if player.Conn == nil {
-			// Player has gone away; probably handled elsewhere
-			return nil
-		}
+// sendToPlayer writes a message to a player's connection, closing it if the
+// write fails. A failed write leaves the connection unusable, so there is no
+// point retrying, and the deadline stops a stalled client from holding up
+// the room.
+func sendToPlayer(player *types.Player, msg types.ToPlayerMessage) error {
+	if player.Conn == nil {
+		// Player has gone away; probably handled elsewhere
+		return nil
+	}
+	err := player.Conn.SetWriteDeadline(time.Now().Add(PLAYER_WRITE_TIMEOUT))
+	if err == nil {
 		err = player.Conn.WriteJSON(msg)
-		if err == nil {
-			return nil
-		}
-		if isClosedConnectionError(err.Error()) {
-			player.Conn.Close()
-			return err
-		}
-		log.Printf("error sending state to player %s: %s", player.Id, err.Error())
-		time.Sleep(backoff)
-		backoff *= 2
 	}
-	player.Conn.Close()
+	if err != nil {
+		log.Printf("error sending message to player %s: %s", player.Id, err.Error())
+		player.Conn.Close()
+	}
 	return err
 }
 
+// rejoin reconnects a player who already has a seat in the room. They sit
+// out until they say they are ready again. The caller must hold the room's
+// lock and its player map's.
+func (r *room) rejoin(player *types.Player, conn *websocket.Conn) {
+	player.Conn = conn
+	player.RoomId = r.id
+	player.Ready = false
+	player.SittingOut = true
+	if r.gameTable != nil && getPlayerState(player.Id, r.gameTable).ID == player.Id {
+		r.gameTable.SetPlayerDefaulting(player.Id, true)
+		r.tableLog.Record(replay.Event{
+			Type:       replay.EventSetDefaulting,
+			PlayerId:   player.Id,
+			Defaulting: true,
+		})
+	}
+}
+
 func (r *room) handlePlayerError(player *types.Player, err error) {
 	log.Printf("connection to %s closed with %s", player.Id, err.Error())
+	if _, standing := r.standing[player.Id]; standing {
+		delete(r.standing, player.Id)
+		player.Conn = nil
+		go r.closeIfEmpty()
+		return
+	}
 	log.Printf("%s is sitting out pending reconnection", player.Id)
 	player.Conn = nil
 	player.SittingOut = true
@@ -594,6 +1320,11 @@ func (r *room) handlePlayerError(player *types.Player, err error) {
 	})
 	if r.gameTable != nil {
 		r.gameTable.SetPlayerDefaulting(player.Id, true)
+		r.tableLog.Record(replay.Event{
+			Type:       replay.EventSetDefaulting,
+			PlayerId:   player.Id,
+			Defaulting: true,
+		})
 		if r.gameTable.State().Active.ID == player.Id {
 			r.handleMessageFromPlayer(
 				types.FromPlayerMessage{
@@ -609,15 +1340,20 @@ func (r *room) handlePlayerError(player *types.Player, err error) {
 }
 
 func (r *room) closeIfEmpty() {
-	r.playerMap.RLock()
-	defer r.playerMap.RUnlock()
-	for _, p := range r.playerMap.players {
-		if p.Conn != nil {
-			return
-		}
+	// Tournament tables are closed by their coordinator until the
+	// tournament is over
+	if r.coordinator != nil && !r.coordinator.isFinished() {
+		return
+	}
+	r.Lock()
+	cancelCh := r.cancelSelfDestructCh
+	empty := r.isEmpty()
+	r.Unlock()
+	if !empty {
+		return
 	}
 	select {
-	case <-r.cancelSelfDestructCh:
+	case <-cancelCh:
 		return
 	case <-time.After(30 * time.Second):
 	}
@@ -626,13 +1362,57 @@ func (r *room) closeIfEmpty() {
 	// TODO remove
 	roomLock.Lock()
 	defer roomLock.Unlock()
+	r.Lock()
+	defer r.Unlock()
+	// Someone may have connected just as the countdown began
+	if !r.isEmpty() {
+		return
+	}
 	log.Printf("room %s destroyed due to inactivity", r.id)
+	r.stopActionTimer()
 	if r.id == "pocket2s" {
 		r.gameTable = nil
+		r.handsPlayed = 0
+		r.rotation = gameRotation{}
+		r.tournament = tournamentState{}
+		r.rebuys = newRebuyState()
+		r.ledger = ledger{}
+		r.buyIns = make(map[string]int)
+		r.leaving = make(map[string]bool)
+		r.standing = make(map[string]*types.Player)
+		r.seatChanges = nil
+		r.seatsChanged = false
+		r.straddles = make(map[string]bool)
+		r.antes = anteState{}
+		r.showdown = showdownState{}
 		r.playerMap.players = make(map[string]*types.Player, MAX_PLAYERS)
+		r.persist()
 		return
 	}
 	delete(roomMap, r.id)
+	if r.coordinator != nil {
+		r.coordinator.closeFinishedTable(r)
+		return
+	}
+	err := roomStore.DeleteRoom(r.id)
+	if err != nil {
+		log.Printf("error deleting room %s from store: %s", r.id, err.Error())
+	}
+}
+
+// isEmpty reports whether no one in the room is connected.
+func (r *room) isEmpty() bool {
+	if len(r.standing) > 0 {
+		return false
+	}
+	r.playerMap.RLock()
+	defer r.playerMap.RUnlock()
+	for _, p := range r.playerMap.players {
+		if p.Conn != nil {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *room) cancelSelfDestruct() {
@@ -640,44 +1420,6 @@ func (r *room) cancelSelfDestruct() {
 	r.cancelSelfDestructCh = make(chan struct{})
 }
 
-func getResult(tableState table.State) string {
-	if tableState.Result.Winners == nil ||
-		tableState.Result.Contestants == nil ||
-		tableState.Result.TableCards == nil {
-		return ""
-	}
-	if len(tableState.Result.Contestants) == 1 {
-		return fmt.Sprintf("%s wins.", tableState.Result.Winners[0].ID)
-	}
-	resultStr := ""
-	/*resultStr := fmt.Sprintf("Table cards: %v\n", tableState.Result.TableCards)
-	for _, c := range tableState.Result.Contestants {
-		resultStr += fmt.Sprintf("%s: %v\n", c.ID, c.Cards)
-	}*/
-	winningHands := make([]string, len(tableState.Result.Winners))
-	var h *hand.Hand
-	for i, winner := range tableState.Result.Winners {
-		h = hand.New(append(winner.Cards, tableState.Result.TableCards...))
-		winningHands[i] = h.Description()
-	}
-	if len(winningHands) == 1 {
-		resultStr += fmt.Sprintf(
-			"%s wins with %s",
-			tableState.Result.Winners[0].ID,
-			winningHands[0])
-		return resultStr
-	}
-	for _, winner := range tableState.Result.Winners {
-		resultStr += winner.ID + ", "
-	}
-	resultStr += "split the pot with "
-	for _, handStr := range winningHands {
-		resultStr += handStr + ", "
-	}
-	resultStr += "respectively."
-	return resultStr
-}
-
 func isClosedConnectionError(errStr string) bool {
 	return strings.Contains(errStr, "use of closed network connection") ||
 		strings.Contains(errStr, "Broken pipe") ||
@@ -0,0 +1,182 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/replay"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// newTestRoom returns a room with the given options, and players with the
+// given IDs seated in order and ready, so that the first hand has been dealt.
+// None of them are connected, so nothing is sent to them.
+func newTestRoom(t *testing.T, opts roomOpts, playerIds ...string) *room {
+	t.Helper()
+	if opts.BuyIn == 0 {
+		opts.BuyIn = 100
+	}
+	if opts.BigBlind == 0 {
+		opts.SmallBlind, opts.BigBlind = 1, 2
+	}
+	err := opts.validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newRoom(t.Name(), opts)
+	for i, playerId := range playerIds {
+		r.playerMap.players[playerId] = &types.Player{Id: playerId, TablePos: i}
+	}
+	for _, playerId := range playerIds {
+		sendReady(r, playerId)
+	}
+	if r.gameTable == nil {
+		t.Fatal("no hand was dealt")
+	}
+	return r
+}
+
+func sendReady(r *room, playerId string) {
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{Type: types.MessageTypeReady},
+		r.playerMap.players[playerId])
+}
+
+// act makes each action as whoever's turn it is, failing the test if the
+// server refuses it.
+func act(t *testing.T, r *room, actions ...table.Action) {
+	t.Helper()
+	for _, action := range actions {
+		playerId := r.gameTable.Active().ID
+		events := len(r.tableLog.Log().Events)
+		r.handleMessageFromPlayer(
+			types.FromPlayerMessage{Type: types.MessageTypePlayerAction, Action: action},
+			r.playerMap.players[playerId])
+		if len(r.tableLog.Log().Events) == events {
+			t.Fatalf("%s by %s was refused", action.Type, playerId)
+		}
+	}
+}
+
+func stacks(state table.State) map[string]int {
+	chips := make(map[string]int, len(state.Seats))
+	for _, seat := range state.Seats {
+		chips[seat.ID] = seat.Chips
+	}
+	return chips
+}
+
+func TestRejoin(t *testing.T) {
+	r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	player := r.playerMap.players["b"]
+	r.rejoin(player, nil)
+	if player.Ready || !player.SittingOut {
+		t.Fatalf("ready = %t, sitting out = %t; want b sitting out", player.Ready, player.SittingOut)
+	}
+	// The table deals b out until they say they are ready again
+	sendReady(r, "a")
+	sendReady(r, "c")
+	for _, seat := range r.gameTable.State().Seats {
+		if seat.ID == "b" && !seat.SittingOut {
+			t.Error("b was dealt in after rejoining")
+		}
+	}
+}
+
+// checkReplay fails the test unless replaying the room's table log ends in
+// the same state as the live table.
+func checkReplay(t *testing.T, r *room, step string) {
+	t.Helper()
+	states, err := replay.Replay(r.tableLog.Log())
+	if err != nil {
+		t.Fatalf("%s: %s", step, err.Error())
+	}
+	got, want := states[len(states)-1], r.gameTable.State()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: replayed state = %+v; want %+v", step, got, want)
+	}
+}
+
+func TestReplayTableLog(t *testing.T) {
+	r := newTestRoom(t, roomOpts{MinBuyIn: 50, MaxBuyIn: 200}, "a", "b", "c")
+	checkReplay(t, r, "deal")
+	act(t, r, table.Action{Type: table.Call}, table.Action{Type: table.Call})
+	checkReplay(t, r, "preflop")
+	act(t, r, table.Action{Type: table.Check})
+	checkReplay(t, r, "check")
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	checkReplay(t, r, "fold")
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{Type: types.MessageTypeTopUp, Amount: 50},
+		r.playerMap.players["a"])
+	checkReplay(t, r, "top up")
+	r.playerMap.players["d"] = &types.Player{Id: "d", TablePos: 3}
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{Type: types.MessageTypeReady, Amount: 80},
+		r.playerMap.players["d"])
+	checkReplay(t, r, "join")
+	r.handleMessageFromPlayer(
+		types.FromPlayerMessage{Type: types.MessageTypeLeave},
+		r.playerMap.players["c"])
+	checkReplay(t, r, "leave")
+	sendReady(r, "a")
+	sendReady(r, "b")
+	checkReplay(t, r, "second deal")
+	for r.gameTable.State().Status != table.Done {
+		act(t, r, table.Action{Type: table.Fold})
+		checkReplay(t, r, "second hand")
+	}
+	events := make(map[replay.EventType]bool)
+	for _, e := range r.tableLog.Log().Events {
+		events[e.Type] = true
+	}
+	for _, eventType := range []replay.EventType{
+		replay.EventNewRound,
+		replay.EventAction,
+		replay.EventAddPlayer,
+		replay.EventSetChips,
+		replay.EventRemovePlayer,
+	} {
+		if !events[eventType] {
+			t.Errorf("no event of type %d was recorded", eventType)
+		}
+	}
+}
+
+func TestBroadcastFailure(t *testing.T) {
+	r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+	// Losing a player checks in the background whether the room is empty
+	r.Lock()
+	defer r.Unlock()
+	player := r.playerMap.players[r.gameTable.Active().ID]
+	player.Conn = newTestConn(t)
+	player.Conn.Close()
+	r.broadcast(types.ToPlayerMessage{Type: types.MessageTypeTableState})
+	if player.Conn != nil || !player.SittingOut {
+		t.Fatalf("%s is still connected after a failed send", player.Id)
+	}
+	// The player whose turn it was folds, which is broadcast in turn
+	if r.gameTable.Active().ID == player.Id {
+		t.Errorf("%s didn't fold after a failed send", player.Id)
+	}
+}
@@ -0,0 +1,137 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+	"github.com/gocraft/web"
+	"github.com/gorilla/websocket"
+)
+
+// observerSet holds the connections of spectators watching a room. Observers
+// are not players: they are not seated, and only ever see public table state.
+type observerSet struct {
+	sync.RWMutex
+	conns map[*websocket.Conn]struct{}
+}
+
+func handleWatch(ctx *Context, rw web.ResponseWriter, req *web.Request) {
+	roomId := req.PathParams["roomId"]
+	roomLock.RLock()
+	r := roomMap[roomId]
+	roomLock.RUnlock()
+	if r == nil {
+		log.Printf("error: room %s does not exist", roomId)
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if status := r.opts.checkAccess(req.Request); status != 0 {
+		log.Printf("error: an observer gave the wrong password or invite code for room %s", roomId)
+		rw.WriteHeader(status)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(rw, req.Request, nil)
+	if err != nil {
+		log.Printf("error establishing connection: %s", err.Error())
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// Hold the room until the observer has the current state, so they don't
+	// miss a change or see one out of order
+	r.Lock()
+	defer r.Unlock()
+	r.observers.Lock()
+	r.observers.conns[conn] = struct{}{}
+	r.observers.Unlock()
+	log.Printf("an observer is watching room %s", r.id)
+
+	err = conn.WriteJSON(types.ToPlayerMessage{Type: types.MessageTypeHello})
+	if err != nil {
+		log.Printf("error sending \"hello\" message to observer: %s", err.Error())
+	}
+	// A held-back result is sent to everyone once it is decided
+	if r.gameTable != nil && !r.runout.pending {
+		err = conn.WriteJSON(types.ToPlayerMessage{
+			Type:       types.MessageTypeTableState,
+			TableState: obfuscateTableState(r.withAntes(r.gameTable.State()), r.showdown.shown),
+			Antes:      r.antes.posted,
+		})
+		if err != nil {
+			log.Printf("error sending table state to observer: %s", err.Error())
+		}
+	}
+	go listenForObserverMessages(conn, r)
+}
+
+// listenForObserverMessages discards anything an observer sends, and removes
+// them from the room once reading from their connection fails. Once a read
+// has failed, every later read on the connection fails too.
+// @blocking
+func listenForObserverMessages(conn *websocket.Conn, r *room) {
+	var (
+		msg types.FromPlayerMessage
+		err error
+	)
+	for {
+		err = conn.ReadJSON(&msg)
+		if err != nil {
+			if !isClosedConnectionError(err.Error()) {
+				log.Printf("error receiving message from observer: %s", err.Error())
+			}
+			r.removeObserver(conn)
+			return
+		}
+	}
+}
+
+func (r *room) removeObserver(conn *websocket.Conn) {
+	r.observers.Lock()
+	defer r.observers.Unlock()
+	if _, ok := r.observers.conns[conn]; ok {
+		delete(r.observers.conns, conn)
+		conn.Close()
+		log.Printf("an observer has stopped watching room %s", r.id)
+	}
+}
+
+// broadcastToObservers sends a message to every observer, stripped of
+// anything that is private to a player.
+func (r *room) broadcastToObservers(msg types.ToPlayerMessage) {
+	msg.PlayerState = table.Player{}
+	msg.TimeBank = 0
+	r.observers.RLock()
+	failed := make([]*websocket.Conn, 0)
+	for conn := range r.observers.conns {
+		err := conn.WriteJSON(msg)
+		if err != nil {
+			log.Printf("error sending message to observer: %s", err.Error())
+			failed = append(failed, conn)
+		}
+	}
+	r.observers.RUnlock()
+	for _, conn := range failed {
+		r.removeObserver(conn)
+	}
+}
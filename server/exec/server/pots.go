@@ -0,0 +1,221 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/handEval"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// awardPot returns the players with the best hand on the given board out of
+// those contesting the pot, the hand they made and what each of them wins
+// of the given amount. As at the table, odd chips go to the winners closest
+// to the left of the button.
+func awardPot(
+	state table.State,
+	contesting []string,
+	board []hand.Card,
+	amount int,
+) ([]string, *hand.Hand, map[string]int) {
+	var best *hand.Hand
+	winners := make([]string, 0)
+	for _, playerId := range contesting {
+		h := handEval.BestHand(state.Options.Variant, seatCards(state, playerId), board)
+		if best == nil || h.CompareTo(best) > 0 {
+			best = h
+			winners = []string{playerId}
+		} else if h.CompareTo(best) == 0 {
+			winners = append(winners, playerId)
+		}
+	}
+	sort.Slice(winners, func(i, j int) bool {
+		return distanceFromButton(state, winners[i]) < distanceFromButton(state, winners[j])
+	})
+	shares := make(map[string]int, len(winners))
+	for i, playerId := range winners {
+		shares[playerId] = amount / len(winners)
+		if amount%len(winners) > i {
+			shares[playerId]++
+		}
+	}
+	return winners, best, shares
+}
+
+// potResult describes a pot as the table paid it out. The winning hand is
+// left out if everyone else folded.
+func potResult(state table.State, p table.Pot) types.PotResult {
+	result := types.PotResult{
+		Amount:   p.Chips,
+		Eligible: p.Contesting,
+		Winners:  p.Winners,
+		Awarded:  p.Shares,
+	}
+	if len(state.Result.Contestants) > 1 && len(p.Winners) > 0 {
+		best := handEval.BestHand(
+			state.Options.Variant,
+			seatCards(state, p.Winners[0]),
+			state.Result.TableCards)
+		result.Ranking = best.Ranking()
+		result.Hand = best.Description()
+	}
+	return result
+}
+
+// boardPotResult awards the given amount from the table's pot to the best
+// hand on one of the boards it was run out on.
+func boardPotResult(
+	state table.State,
+	p table.Pot,
+	board []hand.Card,
+	boardIndex int,
+	amount int,
+) types.PotResult {
+	winners, best, shares := awardPot(state, p.Contesting, board, amount)
+	return types.PotResult{
+		Amount:   amount,
+		Eligible: p.Contesting,
+		Winners:  winners,
+		Board:    boardIndex,
+		Ranking:  best.Ranking(),
+		Hand:     best.Description(),
+		Awarded:  shares,
+	}
+}
+
+// potResults breaks down who won what from each pot once a hand is over,
+// board by board if it was run more than once. It returns nil if the hand
+// isn't over. Uncalled bets aren't part of any pot; they are in the table's
+// result as returned.
+func (r *room) potResults(state table.State) []types.PotResult {
+	if state.Status != table.Done || len(state.Result.Pots) == 0 {
+		return nil
+	}
+	if len(r.runout.pots) > 0 {
+		return r.runout.pots
+	}
+	pots := make([]types.PotResult, 0, len(state.Result.Pots))
+	for _, p := range state.Result.Pots {
+		pots = append(pots, potResult(state, p))
+	}
+	return pots
+}
+
+// describeReturned describes the uncalled bets handed back at the end of a
+// hand.
+func describeReturned(returned map[string]int) string {
+	playerIds := make([]string, 0, len(returned))
+	for playerId := range returned {
+		playerIds = append(playerIds, playerId)
+	}
+	sort.Strings(playerIds)
+	lines := make([]string, 0, len(playerIds))
+	for _, playerId := range playerIds {
+		lines = append(lines, fmt.Sprintf("Uncalled bet of %d returned to %s.", returned[playerId], playerId))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resultString describes the outcome of a hand from its pot results, board
+// by board if it was run more than once, followed by any uncalled bets that
+// were returned.
+func resultString(pots []types.PotResult, boards [][]hand.Card, returned map[string]int) string {
+	result := describeResult(pots, boards)
+	if result != "" && len(returned) > 0 {
+		result += "\n" + describeReturned(returned)
+	}
+	return result
+}
+
+func describeResult(pots []types.PotResult, boards [][]hand.Card) string {
+	if len(pots) == 0 {
+		return ""
+	}
+	if pots[0].Hand == "" {
+		return fmt.Sprintf("%s wins.", pots[0].Winners[0])
+	}
+	if len(boards) < 2 {
+		return describePots(pots) + "."
+	}
+	lines := make([]string, 0, len(boards))
+	for i, board := range boards {
+		boardPots := make([]types.PotResult, 0)
+		for _, p := range pots {
+			if p.Board == i {
+				boardPots = append(boardPots, p)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("Board %d %v: %s.", i+1, board, describePots(boardPots)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describePots(pots []types.PotResult) string {
+	results := make([]string, 0, len(pots))
+	for i, p := range pots {
+		var result string
+		if len(p.Winners) == 1 {
+			result = fmt.Sprintf("%s wins %d with %s", p.Winners[0], p.Amount, p.Hand)
+		} else {
+			result = fmt.Sprintf("%s split %d with %s", strings.Join(p.Winners, ", "), p.Amount, p.Hand)
+		}
+		if len(pots) > 1 {
+			result = potName(i) + ": " + result
+		}
+		results = append(results, result)
+	}
+	return strings.Join(results, "; ")
+}
+
+func potName(i int) string {
+	if i == 0 {
+		return "main pot"
+	}
+	return fmt.Sprintf("side pot %d", i)
+}
+
+func seatCards(state table.State, playerId string) []hand.Card {
+	for _, seat := range state.Seats {
+		if seat.ID == playerId {
+			return seat.Cards
+		}
+	}
+	return nil
+}
+
+// distanceFromButton counts the seats from the button to the player, going
+// left, with the button itself furthest away.
+func distanceFromButton(state table.State, playerId string) int {
+	n := len(state.Seats)
+	for _, seat := range state.Seats {
+		if seat.ID == playerId {
+			dist := (seat.Seat - state.Button + n) % n
+			if dist == 0 {
+				dist = n
+			}
+			return dist
+		}
+	}
+	return n
+}
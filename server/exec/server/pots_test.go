@@ -0,0 +1,113 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/jokertest"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestPotResults(t *testing.T) {
+	// b is first to act, then c in the small blind and a in the big blind
+	tests := []struct {
+		name    string
+		actions []table.Action
+		pots    []types.PotResult
+		result  string
+	}{
+		{
+			name:    "folded to the big blind",
+			actions: []table.Action{{Type: table.Fold}, {Type: table.Fold}},
+			pots: []types.PotResult{
+				{Amount: 2, Eligible: []string{"a"}, Winners: []string{"a"}, Awarded: map[string]int{"a": 2}},
+			},
+			result: "a wins.\nUncalled bet of 1 returned to a.",
+		},
+		{
+			name:    "uncalled raise",
+			actions: []table.Action{{Type: table.Raise, Chips: 10}, {Type: table.Fold}, {Type: table.Fold}},
+			pots: []types.PotResult{
+				{Amount: 5, Eligible: []string{"b"}, Winners: []string{"b"}, Awarded: map[string]int{"b": 5}},
+			},
+			result: "b wins.\nUncalled bet of 10 returned to b.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+			act(t, r, tt.actions...)
+			state := r.gameTable.State()
+			pots := r.potResults(state)
+			if !reflect.DeepEqual(pots, tt.pots) {
+				t.Errorf("pots = %+v; want %+v", pots, tt.pots)
+			}
+			result := resultString(pots, nil, state.Result.Returned)
+			if result != tt.result {
+				t.Errorf("result = %q; want %q", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestAwardPot(t *testing.T) {
+	// a has the button, so c is closer to its left than a is
+	state := table.State{
+		Seats: []table.Player{
+			{ID: "a", Seat: 0, Cards: jokertest.Cards("As", "7d")},
+			{ID: "b", Seat: 1, Cards: jokertest.Cards("Kd", "Kc")},
+			{ID: "c", Seat: 2, Cards: jokertest.Cards("Ac", "7h")},
+		},
+		Button: 0,
+	}
+	board := jokertest.Cards("Ah", "Ad", "9s", "8c", "2d")
+	tests := []struct {
+		name       string
+		contesting []string
+		amount     int
+		winners    []string
+		shares     map[string]int
+	}{
+		{
+			name:       "best hand",
+			contesting: []string{"a", "b"},
+			amount:     10,
+			winners:    []string{"a"},
+			shares:     map[string]int{"a": 10},
+		},
+		{
+			name:       "split with an odd chip",
+			contesting: []string{"a", "b", "c"},
+			amount:     5,
+			winners:    []string{"c", "a"},
+			shares:     map[string]int{"a": 2, "c": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winners, _, shares := awardPot(state, tt.contesting, board, tt.amount)
+			if !reflect.DeepEqual(winners, tt.winners) || !reflect.DeepEqual(shares, tt.shares) {
+				t.Errorf("winners = %v, shares = %v; want %v and %v", winners, shares, tt.winners, tt.shares)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// rebuyOpts limits how often broke players may buy back in. The rebuy window
+// opens with the room's first hand, and closes after WindowHands hands or
+// WindowMinutes minutes, whichever comes first; with neither set, it never
+// closes, and MaxRebuys must be set. In a multi-table tournament the window
+// is the tournament's, and closes once any table has played WindowHands
+// hands.
+type rebuyOpts struct {
+	// MaxRebuys is the most times each player may rebuy. Zero means no limit.
+	MaxRebuys     int
+	WindowHands   int
+	WindowMinutes int
+	// TopUp, if set, is the number of chips each rebuy gives instead of BuyIn
+	TopUp int
+	// AddOn, if set, is a number of chips each player still in may buy once,
+	// after the rebuy window closes and before the next hand is dealt
+	AddOn int
+}
+
+type rebuyState struct {
+	window *rebuyWindow
+	// addOnOpen is set from the hand that closes the rebuy window at this
+	// table until the next hand is dealt, and addOnOffered once it has been
+	addOnOpen    bool
+	addOnOffered bool
+}
+
+// rebuyWindow tracks the rebuy window and who has rebought. It has its own
+// lock, since every table in a multi-table tournament shares its
+// coordinator's window.
+type rebuyWindow struct {
+	sync.Mutex
+	start  time.Time
+	hands  int
+	closed bool
+	// counts holds the number of times each player has rebought, and addOns
+	// the players who have taken the add-on
+	counts map[string]int
+	addOns map[string]bool
+}
+
+func newRebuyState() rebuyState {
+	return rebuyState{window: &rebuyWindow{}}
+}
+
+func (opts *rebuyOpts) validate() error {
+	if opts.MaxRebuys < 0 || opts.WindowHands < 0 || opts.WindowMinutes < 0 ||
+		opts.TopUp < 0 || opts.AddOn < 0 {
+		return errors.New("rebuy options must not be negative")
+	}
+	if opts.AddOn > 0 && opts.WindowHands == 0 && opts.WindowMinutes == 0 {
+		return errors.New("an add-on needs a rebuy window to follow")
+	}
+	// Otherwise nobody could ever be knocked out of a tournament
+	if opts.MaxRebuys == 0 && opts.WindowHands == 0 && opts.WindowMinutes == 0 {
+		return errors.New("unlimited rebuys need a rebuy window")
+	}
+	return nil
+}
+
+// expired reports whether the window has run its course. The caller must
+// hold the window's lock.
+func (w *rebuyWindow) expired(opts *rebuyOpts) bool {
+	if w.start.IsZero() {
+		return false
+	}
+	return (opts.WindowHands > 0 && w.hands >= opts.WindowHands) ||
+		(opts.WindowMinutes > 0 &&
+			time.Since(w.start) >= time.Duration(opts.WindowMinutes)*time.Minute)
+}
+
+// canRebuy reports whether the player could rebuy if they went broke now.
+func (r *room) canRebuy(playerId string) bool {
+	opts := r.opts.Rebuys
+	if opts == nil {
+		return false
+	}
+	w := r.rebuys.window
+	w.Lock()
+	defer w.Unlock()
+	return !w.closed && (opts.MaxRebuys == 0 || w.counts[playerId] < opts.MaxRebuys)
+}
+
+// startRebuyHand must be called once a hand has been dealt. The first hand
+// opens the rebuy window, and any hand closes the add-on.
+func (r *room) startRebuyHand() {
+	if r.opts.Rebuys == nil {
+		return
+	}
+	w := r.rebuys.window
+	w.Lock()
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	w.Unlock()
+	r.rebuys.addOnOpen = false
+}
+
+// endRebuyHand must be called once a hand is over, and before any players are
+// eliminated from a tournament. It closes the rebuy window once it has run
+// its course, and opens the add-on at this table the first time it finishes
+// a hand with the window closed.
+func (r *room) endRebuyHand() {
+	if r.opts.Rebuys == nil || r.rebuys.addOnOffered {
+		return
+	}
+	w := r.rebuys.window
+	w.Lock()
+	defer w.Unlock()
+	if !w.closed {
+		// The window lasts as many hands as the table that has played the
+		// most, which is this one unless the room is a tournament table
+		if r.handsPlayed+1 > w.hands {
+			w.hands = r.handsPlayed + 1
+		}
+		if !w.expired(r.opts.Rebuys) {
+			return
+		}
+		w.closed = true
+		log.Printf("rebuys are closed in room %s", r.id)
+	}
+	r.rebuys.addOnOffered = true
+	r.rebuys.addOnOpen = r.opts.Rebuys.AddOn > 0
+}
+
+// rebuy buys a broke player back in, within the room's rebuy rules. It tells
+// the player why and returns false if they may not.
+func (r *room) rebuy(player *types.Player, requested int) bool {
+	opts := r.opts.Rebuys
+	w := r.rebuys.window
+	w.Lock()
+	count, closed := w.counts[player.Id], w.closed
+	w.Unlock()
+	switch {
+	case closed:
+		r.sendError(player, types.ErrorCodeRebuyNotAllowed, "Sorry, rebuys are closed.")
+		return false
+	case opts.MaxRebuys > 0 && count >= opts.MaxRebuys:
+		r.sendError(
+			player,
+			types.ErrorCodeRebuyLimitReached,
+			fmt.Sprintf("Sorry, you've used all %d of your rebuys.", opts.MaxRebuys))
+		return false
+	case !r.betweenHands():
+		r.sendError(player, types.ErrorCodeRebuyNotAllowed, "Sorry, you can only rebuy between hands.")
+		return false
+	case getPlayerState(player.Id, r.gameTable).Chips > 0:
+		r.sendError(
+			player,
+			types.ErrorCodeRebuyNotAllowed,
+			"Sorry, you can only rebuy once you've run out of chips.")
+		return false
+	}
+
+	amount := opts.TopUp
+	if amount == 0 {
+		var err error
+		amount, err = r.buyInAmount(requested)
+		if err != nil {
+			r.sendError(player, types.ErrorCodeBuyInOutOfRange, err.Error())
+			return false
+		}
+	}
+	added, err := r.buyBackIn(player.Id, amount)
+	if err != nil {
+		log.Printf("error buying %s in: %s", player.Id, err.Error())
+		r.sendError(player, types.ErrorCodeBuyInFailed, "Sorry, we couldn't buy you in.")
+		return false
+	}
+	w.Lock()
+	if w.counts == nil {
+		w.counts = make(map[string]int)
+	}
+	w.counts[player.Id] = count + 1
+	w.Unlock()
+	r.recordLedger(LedgerEntryRebuy, player.Id, added)
+	log.Printf("%s has rebought %d times in room %s", player.Id, count+1, r.id)
+	r.addPrizeEntry()
+	return true
+}
+
+// addOn sells the player the add-on, if it is open and they haven't had it.
+func (r *room) addOn(player *types.Player) {
+	if r.gameTable == nil || !r.rebuys.addOnOpen {
+		r.sendError(player, types.ErrorCodeAddOnNotAllowed, "Sorry, the add-on isn't available now.")
+		return
+	}
+	w := r.rebuys.window
+	w.Lock()
+	taken := w.addOns[player.Id]
+	w.Unlock()
+	if taken {
+		r.sendError(player, types.ErrorCodeAddOnNotAllowed, "Sorry, you've already taken the add-on.")
+		return
+	}
+	chips := getPlayerState(player.Id, r.gameTable).Chips
+	if chips == 0 {
+		r.sendError(player, types.ErrorCodeAddOnNotAllowed, "Sorry, you need chips to take the add-on.")
+		return
+	}
+	r.setChips(player.Id, chips+r.opts.Rebuys.AddOn)
+	w.Lock()
+	if w.addOns == nil {
+		w.addOns = make(map[string]bool)
+	}
+	w.addOns[player.Id] = true
+	w.Unlock()
+	r.recordLedger(LedgerEntryAddOn, player.Id, r.opts.Rebuys.AddOn)
+	log.Printf("%s has taken the add-on in room %s", player.Id, r.id)
+	r.addPrizeEntry()
+}
+
+// addPrizeEntry adds another entry fee to a tournament's prize pool for a
+// rebuy or add-on.
+func (r *room) addPrizeEntry() {
+	if !r.isTournament() {
+		return
+	}
+	if r.coordinator != nil {
+		r.coordinator.addEntry()
+		return
+	}
+	r.tournament.entries++
+}
@@ -0,0 +1,63 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestRebuyOptsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    rebuyOpts
+		wantErr bool
+	}{
+		{name: "limited rebuys without a window", opts: rebuyOpts{MaxRebuys: 2}},
+		{name: "unlimited rebuys in a window", opts: rebuyOpts{WindowHands: 10}},
+		{name: "unlimited rebuys forever", opts: rebuyOpts{}, wantErr: true},
+		{name: "add-on without a window", opts: rebuyOpts{MaxRebuys: 1, AddOn: 100}, wantErr: true},
+		{name: "negative limit", opts: rebuyOpts{MaxRebuys: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v; want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddOn(t *testing.T) {
+	opts := roomOpts{Rebuys: &rebuyOpts{WindowHands: 1, AddOn: 50}}
+	r := newTestRoom(t, opts, "a", "b", "c")
+	// b and c fold, so a wins the blinds with their own big blind still in
+	// the pot
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	if !r.rebuys.addOnOpen {
+		t.Fatal("the add-on didn't open when the rebuy window closed")
+	}
+	r.handleMessageFromPlayer(types.FromPlayerMessage{Type: types.MessageTypeAddOn}, r.playerMap.players["a"])
+	if got := stacks(r.gameTable.State())["a"]; got != 151 {
+		t.Errorf("a has %d chips after the add-on; want 151", got)
+	}
+}
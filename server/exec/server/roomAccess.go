@@ -0,0 +1,58 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+type roomCheckResponse struct {
+	Protected bool
+}
+
+func (opts *roomOpts) isProtected() bool {
+	return opts.Password != "" || opts.InviteCode != ""
+}
+
+// checkAccess checks the "password" or "invite" query parameters of a
+// request against a protected room, returning the HTTP status to reject the
+// request with, or 0 if it may proceed. A valid invite code grants access
+// without the password.
+func (opts *roomOpts) checkAccess(req *http.Request) int {
+	if !opts.isProtected() {
+		return 0
+	}
+	query := req.URL.Query()
+	password, invite := query.Get("password"), query.Get("invite")
+	if password == "" && invite == "" {
+		return http.StatusUnauthorized
+	}
+	if opts.InviteCode != "" && secretsMatch(invite, opts.InviteCode) {
+		return 0
+	}
+	if opts.Password != "" && secretsMatch(password, opts.Password) {
+		return 0
+	}
+	return http.StatusForbidden
+}
+
+func secretsMatch(given string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
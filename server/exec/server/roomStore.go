@@ -0,0 +1,264 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// RoomStore persists rooms so that games can survive a server restart.
+type RoomStore interface {
+	SaveRoom(record RoomRecord) error
+	DeleteRoom(roomId string) error
+	LoadRooms() ([]RoomRecord, error)
+}
+
+type RoomRecord struct {
+	Id      string
+	Opts    roomOpts
+	Players []PlayerRecord
+	Ledger  []LedgerEntry
+	// Tournament is set once a tournament has started, so that it carries on
+	// at the same blind level with the same standings
+	Tournament *TournamentRecord `json:",omitempty"`
+}
+
+type PlayerRecord struct {
+	Id       string
+	TablePos int
+	Chips    int
+	TimeBank int
+	// SessionNonce identifies the player's current session token
+	SessionNonce string
+}
+
+type memoryRoomStore struct {
+	sync.RWMutex
+	rooms map[string]RoomRecord
+}
+
+func newMemoryRoomStore() *memoryRoomStore {
+	return &memoryRoomStore{rooms: make(map[string]RoomRecord)}
+}
+
+func (s *memoryRoomStore) SaveRoom(record RoomRecord) error {
+	s.Lock()
+	defer s.Unlock()
+	s.rooms[record.Id] = record
+	return nil
+}
+
+func (s *memoryRoomStore) DeleteRoom(roomId string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.rooms, roomId)
+	return nil
+}
+
+func (s *memoryRoomStore) LoadRooms() ([]RoomRecord, error) {
+	s.RLock()
+	defer s.RUnlock()
+	records := make([]RoomRecord, 0, len(s.rooms))
+	for _, record := range s.rooms {
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// fileRoomStore keeps every room in a single JSON file, which is rewritten
+// in full on every change.
+type fileRoomStore struct {
+	sync.Mutex
+	path string
+}
+
+func newFileRoomStore(path string) *fileRoomStore {
+	return &fileRoomStore{path: path}
+}
+
+func (s *fileRoomStore) SaveRoom(record RoomRecord) error {
+	s.Lock()
+	defer s.Unlock()
+	rooms, err := s.read()
+	if err != nil {
+		return err
+	}
+	rooms[record.Id] = record
+	return s.write(rooms)
+}
+
+func (s *fileRoomStore) DeleteRoom(roomId string) error {
+	s.Lock()
+	defer s.Unlock()
+	rooms, err := s.read()
+	if err != nil {
+		return err
+	}
+	delete(rooms, roomId)
+	return s.write(rooms)
+}
+
+func (s *fileRoomStore) LoadRooms() ([]RoomRecord, error) {
+	s.Lock()
+	defer s.Unlock()
+	rooms, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]RoomRecord, 0, len(rooms))
+	for _, record := range rooms {
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (s *fileRoomStore) read() (map[string]RoomRecord, error) {
+	rooms := make(map[string]RoomRecord)
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return rooms, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &rooms)
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func (s *fileRoomStore) write(rooms map[string]RoomRecord) error {
+	data, err := json.Marshal(rooms)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a crash mid-write can't
+	// corrupt the store
+	tmpPath := s.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (r *room) record() RoomRecord {
+	r.playerMap.RLock()
+	defer r.playerMap.RUnlock()
+	record := RoomRecord{
+		Id:      r.id,
+		Opts:    r.opts,
+		Players: make([]PlayerRecord, 0, len(r.playerMap.players)),
+		Ledger:  r.ledger.getEntries(),
+	}
+	if r.tournament.started {
+		record.Tournament = r.tournamentRecord()
+	}
+	// A hand in progress can't be restored, so everyone gets back what
+	// they have put in the pot
+	inHand := r.gameTable != nil && r.gameTable.State().Status != table.Done
+	for _, player := range r.playerMap.players {
+		chips := r.opts.BuyIn
+		if restored, ok := r.restoredChips[player.Id]; ok {
+			chips = restored
+		}
+		if r.gameTable != nil {
+			pState := getPlayerState(player.Id, r.gameTable)
+			if pState.ID == player.Id {
+				chips = pState.Chips
+				if inHand {
+					chips += r.chipsInPot(pState)
+				}
+			}
+		}
+		record.Players = append(record.Players, PlayerRecord{
+			Id:           player.Id,
+			TablePos:     player.TablePos,
+			Chips:        chips,
+			TimeBank:     player.TimeBank,
+			SessionNonce: player.SessionNonce,
+		})
+	}
+	sort.Slice(record.Players, func(i, j int) bool {
+		return record.Players[i].TablePos < record.Players[j].TablePos
+	})
+	return record
+}
+
+func (r *room) persist() {
+	// Tournament tables cannot be restored without their coordinator
+	if r.coordinator != nil {
+		return
+	}
+	err := roomStore.SaveRoom(r.record())
+	if err != nil {
+		log.Printf("error saving room %s: %s", r.id, err.Error())
+	}
+}
+
+// restoreRooms repopulates the room map from the room store. Restored players
+// sit out until they reconnect, and get their chip counts back once the table
+// is dealt.
+func restoreRooms() {
+	records, err := roomStore.LoadRooms()
+	if err != nil {
+		log.Printf("error loading rooms: %s", err.Error())
+		return
+	}
+	roomLock.Lock()
+	defer roomLock.Unlock()
+	for _, record := range records {
+		r := newRoom(record.Id, record.Opts)
+		r.restoredChips = make(map[string]int, len(record.Players))
+		r.ledger.entries = record.Ledger
+		if record.Tournament != nil {
+			r.restoreTournament(*record.Tournament)
+		}
+		for _, p := range record.Players {
+			r.playerMap.players[p.Id] = &types.Player{
+				Id:           p.Id,
+				TablePos:     p.TablePos,
+				SittingOut:   true,
+				Broke:        p.Chips == 0,
+				TimeBank:     p.TimeBank,
+				SessionNonce: p.SessionNonce,
+			}
+			r.restoredChips[p.Id] = p.Chips
+		}
+		roomMap[record.Id] = r
+		log.Printf("restored room %s with %d players", record.Id, len(record.Players))
+	}
+}
+
+// applyRestoredChips gives restored players back the chip counts they had
+// before the server restarted. It must be called once the table has been
+// created.
+func (r *room) applyRestoredChips() {
+	r.setStacks(r.restoredChips)
+	r.restoredChips = nil
+}
@@ -0,0 +1,98 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestRestoreTournament(t *testing.T) {
+	roomStore = newMemoryRoomStore()
+	opts := roomOpts{
+		Tournament: &tournamentOpts{
+			Levels: []blindLevel{
+				{SmallBlind: 1, BigBlind: 2, Hands: 1},
+				{SmallBlind: 2, BigBlind: 4},
+			},
+			EntryFee: 10,
+			Payouts:  []int{70, 30},
+		},
+	}
+	r := newTestRoom(t, opts, "a", "b", "c")
+	r.tournament.level = 1
+	r.tournament.levelHands = 3
+	r.addStanding("c", 3)
+	r.persist()
+
+	restoreRooms()
+	restored := roomMap[r.id]
+	if restored == nil {
+		t.Fatal("room wasn't restored")
+	}
+	if !restored.tournament.started || restored.tournament.entrants != 3 || restored.tournament.entries != 3 {
+		t.Errorf("restored entry = %+v; want started with 3 entrants", restored.tournament)
+	}
+	if restored.tournament.level != 1 || restored.tournament.levelHands != 3 {
+		t.Errorf("restored level %d after %d hands; want level 1 after 3 hands",
+			restored.tournament.level, restored.tournament.levelHands)
+	}
+	want := []types.Standing{{PlayerId: "c", Place: 3}}
+	if !reflect.DeepEqual(restored.tournament.standings, want) {
+		t.Errorf("restored standings = %+v; want %+v", restored.tournament.standings, want)
+	}
+	if stakes := restored.stakes(); stakes.BigBlind != 4 {
+		t.Errorf("restored big blind = %d; want 4", stakes.BigBlind)
+	}
+}
+
+func TestRecordStacks(t *testing.T) {
+	// a is the big blind and c the small blind
+	tests := []struct {
+		name    string
+		actions []table.Action
+		want    map[string]int
+	}{
+		{
+			name: "mid-hand",
+			want: map[string]int{"a": 100, "b": 100, "c": 100},
+		},
+		{
+			name:    "between hands",
+			actions: []table.Action{{Type: table.Fold}, {Type: table.Fold}},
+			want:    map[string]int{"a": 101, "b": 100, "c": 99},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+			act(t, r, tt.actions...)
+			got := make(map[string]int)
+			for _, player := range r.record().Players {
+				got[player.Id] = player.Chips
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recorded stacks = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
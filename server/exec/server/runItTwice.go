@@ -0,0 +1,245 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+const (
+	DEFAULT_RUN_IT_TIMES = 2
+	MAX_RUN_IT_TIMES     = 3
+	// RUN_IT_TWICE_TIMEOUT is how long players have to agree to run it
+	// twice in rooms without an action timer
+	RUN_IT_TWICE_TIMEOUT = 30 * time.Second
+)
+
+// runout tracks an offer to run the board more than once, and the boards
+// dealt if everyone agreed.
+type runout struct {
+	pending bool
+	seq     int
+	// board is the board when the betting closed, and answers holds the
+	// answer of each player still in the hand who has given one
+	board   []hand.Card
+	answers map[string]bool
+	timer   *time.Timer
+	// boards holds every board dealt, and pots who won what on each
+	boards [][]hand.Card
+	pots   []types.PotResult
+}
+
+func (opts *roomOpts) validateRunItTwice() error {
+	if opts.RunItTimes != 0 && !opts.RunItTwice {
+		return errors.New("RunItTimes needs RunItTwice")
+	}
+	if opts.RunItTimes != 0 && (opts.RunItTimes < 2 || opts.RunItTimes > MAX_RUN_IT_TIMES) {
+		return fmt.Errorf("the board can be run between 2 and %d times", MAX_RUN_IT_TIMES)
+	}
+	return nil
+}
+
+// betweenHands reports whether there is no hand in progress. A hand that has
+// been played out isn't over until everyone has decided whether to run it
+// twice, since until then its result is held back.
+func (r *room) betweenHands() bool {
+	return r.gameTable == nil || (r.gameTable.State().Status == table.Done && !r.runout.pending)
+}
+
+func (opts *roomOpts) runItTimes() int {
+	if opts.RunItTimes == 0 {
+		return DEFAULT_RUN_IT_TIMES
+	}
+	return opts.RunItTimes
+}
+
+// offerRunItTwice must be called after every action. If the action ended the
+// hand with players all in before the river, it holds back the result and
+// asks everyone still in the hand whether to run the rest of the board more
+// than once, returning true.
+func (r *room) offerRunItTwice(before table.State, state table.State) bool {
+	if !r.opts.RunItTwice || state.Status != table.Done ||
+		len(state.Result.Contestants) < 2 || len(before.Cards) >= 5 {
+		return false
+	}
+	contestants := make([]*types.Player, 0, len(state.Result.Contestants))
+	r.playerMap.RLock()
+	for _, contestant := range state.Result.Contestants {
+		p := r.playerMap.players[contestant.ID]
+		if p == nil || p.Conn == nil {
+			// Nobody can agree on behalf of a player who isn't here
+			r.playerMap.RUnlock()
+			return false
+		}
+		contestants = append(contestants, p)
+	}
+	r.playerMap.RUnlock()
+
+	r.stopActionTimer()
+	r.runout.pending = true
+	r.runout.seq++
+	r.runout.board = append([]hand.Card(nil), before.Cards...)
+	r.runout.answers = make(map[string]bool, len(state.Result.Contestants))
+	timeout := RUN_IT_TWICE_TIMEOUT
+	if r.opts.ActionTimeout > 0 {
+		timeout = time.Duration(r.opts.ActionTimeout) * time.Second
+	}
+	seq := r.runout.seq
+	r.runout.timer = time.AfterFunc(timeout, func() {
+		r.Lock()
+		defer r.Unlock()
+		if !r.runout.pending || r.runout.seq != seq {
+			return
+		}
+		log.Printf("not everyone in room %s answered in time; running it once", r.id)
+		r.resolveRunItTwice(seq, false)
+	})
+	log.Printf("offering to run it %d times in room %s", r.opts.runItTimes(), r.id)
+	for _, p := range contestants {
+		err := sendToPlayer(p, types.ToPlayerMessage{
+			Type:       types.MessageTypeRunItTwice,
+			RunItTimes: r.opts.runItTimes(),
+		})
+		if err != nil {
+			log.Printf("error offering to run it twice to %s: %s", p.Id, err.Error())
+		}
+	}
+	return true
+}
+
+// answerRunItTwice records a player's answer to the offer. A single refusal
+// runs the board once, and it is run more than once as soon as everyone has
+// agreed.
+func (r *room) answerRunItTwice(player *types.Player, agree bool) {
+	if !r.runout.pending {
+		r.sendError(player, types.ErrorCodeNoGameInProgress, "Nobody has offered to run it twice.")
+		return
+	}
+	state := r.gameTable.State()
+	if !playerIsContesting(player.Id, state) {
+		r.sendError(player, types.ErrorCodeNotYourTurn, "You're not in this hand.")
+		return
+	}
+	r.runout.answers[player.Id] = agree
+	if !agree {
+		log.Printf("%s declined to run it twice in room %s", player.Id, r.id)
+		r.resolveRunItTwice(r.runout.seq, false)
+		return
+	}
+	for _, contestant := range state.Result.Contestants {
+		if !r.runout.answers[contestant.ID] {
+			return
+		}
+	}
+	r.resolveRunItTwice(r.runout.seq, true)
+}
+
+func (r *room) resolveRunItTwice(seq int, agreed bool) {
+	if !r.runout.pending || r.runout.seq != seq {
+		return
+	}
+	r.runout.pending = false
+	r.runout.timer.Stop()
+	if agreed {
+		r.runItMore(r.gameTable.State())
+	}
+	r.broadcastState(r.gameTable.State())
+}
+
+// runItMore deals the rest of the board again from the cards nobody has
+// seen, splits each pot evenly between the boards and corrects the stacks
+// the table paid out for its single board.
+func (r *room) runItMore(state table.State) {
+	dealt := make(map[hand.Card]bool)
+	for _, seat := range state.Seats {
+		for _, card := range seat.Cards {
+			dealt[card] = true
+		}
+	}
+	for _, card := range state.Cards {
+		dealt[card] = true
+	}
+	deck := make([]hand.Card, 0, 52)
+	for _, card := range hand.Cards() {
+		if !dealt[card] {
+			deck = append(deck, card)
+		}
+	}
+	rand.New(r.randSrc).Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+
+	need := 5 - len(r.runout.board)
+	boards := [][]hand.Card{state.Cards}
+	for len(boards) < r.opts.runItTimes() && len(deck) >= need {
+		board := append(append([]hand.Card(nil), r.runout.board...), deck[:need]...)
+		deck = deck[need:]
+		boards = append(boards, board)
+	}
+	if len(boards) == 1 {
+		log.Printf("not enough cards left to run it twice in room %s", r.id)
+		return
+	}
+
+	// The table has already paid out every pot on the first board
+	paid := make(map[string]int)
+	for _, tablePot := range state.Result.Pots {
+		for playerId, share := range tablePot.Shares {
+			paid[playerId] += share
+		}
+	}
+	won := make(map[string]int)
+	boardPots := make([][]types.PotResult, len(boards))
+	for _, tablePot := range state.Result.Pots {
+		for i, board := range boards {
+			amount := tablePot.Chips / len(boards)
+			if tablePot.Chips%len(boards) > i {
+				amount++
+			}
+			result := boardPotResult(state, tablePot, board, i, amount)
+			for playerId, share := range result.Awarded {
+				won[playerId] += share
+			}
+			boardPots[i] = append(boardPots[i], result)
+		}
+	}
+	pots := make([]types.PotResult, 0)
+	for _, results := range boardPots {
+		pots = append(pots, results...)
+	}
+	for _, seat := range state.Seats {
+		diff := won[seat.ID] - paid[seat.ID]
+		if diff == 0 {
+			continue
+		}
+		r.setChips(seat.ID, seat.Chips+diff)
+	}
+	r.history.RecordRunouts(boards, won)
+	r.runout.boards = boards
+	r.runout.pots = pots
+	log.Printf("ran it %d times in room %s", len(boards), r.id)
+}
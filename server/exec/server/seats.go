@@ -0,0 +1,290 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// leave stands a player up from the table and cashes them out. They stay
+// connected, and can sit back down by saying they are ready. Players who ask
+// to leave during a hand play it out, and are removed once it is over.
+func (r *room) leave(player *types.Player) {
+	if r.isTournament() {
+		r.sendError(
+			player,
+			types.ErrorCodeLeaveNotAllowed,
+			"Sorry, you can't leave a tournament. Sit out instead.")
+		return
+	}
+	if !r.betweenHands() {
+		r.leaving[player.Id] = true
+		log.Printf("%s will leave room %s after this hand", player.Id, r.id)
+		return
+	}
+	r.removeLeaver(player)
+	r.processSeatChanges()
+	r.persist()
+	// Everyone else may have been waiting on the player who left
+	var ready *types.Player
+	r.playerMap.RLock()
+	for _, p := range r.playerMap.players {
+		if p.Ready {
+			ready = p
+			break
+		}
+	}
+	r.playerMap.RUnlock()
+	if ready != nil && r.playersAreReady() {
+		r.handleMessageFromPlayer(types.FromPlayerMessage{Type: types.MessageTypeReady}, ready)
+	}
+}
+
+// removeLeavers removes the players who asked to leave during the hand that
+// has just finished.
+func (r *room) removeLeavers() {
+	for playerId := range r.leaving {
+		r.playerMap.RLock()
+		player := r.playerMap.players[playerId]
+		r.playerMap.RUnlock()
+		if player != nil {
+			r.removeLeaver(player)
+		}
+	}
+}
+
+func (r *room) removeLeaver(player *types.Player) {
+	chips := r.removePlayer(player.Id)
+	player.Ready = false
+	player.SittingOut = true
+	log.Printf("%s has left room %s with %d chips", player.Id, r.id, chips)
+	if player.Conn != nil {
+		err := sendToPlayer(player, types.ToPlayerMessage{
+			Type:     types.MessageTypeLeave,
+			PlayerId: player.Id,
+			CashOut:  chips,
+		})
+		if err != nil {
+			log.Printf("error confirming %s has left: %s", player.Id, err.Error())
+		}
+	}
+	if player.Conn != nil {
+		r.standing[player.Id] = player
+	}
+	r.broadcast(types.ToPlayerMessage{
+		Type:     types.MessageTypeLeave,
+		PlayerId: player.Id,
+	})
+	go r.closeIfEmpty()
+}
+
+type seatChange struct {
+	playerId string
+	tablePos int
+}
+
+// maxPlayers returns the number of seats at the table.
+func (opts *roomOpts) maxPlayers() int {
+	if opts.MaxPlayers == 0 {
+		return MAX_PLAYERS
+	}
+	return opts.MaxPlayers
+}
+
+// seatHolder returns the player in the given seat, or nil if it is free. The
+// caller must hold the player map lock.
+func (r *room) seatHolder(tablePos int) *types.Player {
+	for _, player := range r.playerMap.players {
+		if player.TablePos == tablePos {
+			return player
+		}
+	}
+	return nil
+}
+
+// requestedTablePos converts the seat a joining player asked for, counting
+// from 1, to a TablePos. It returns a non-zero HTTP status if the seat does
+// not exist or is taken. The caller must hold the player map lock.
+func (r *room) requestedTablePos(seat string) (int, int) {
+	n, err := strconv.Atoi(seat)
+	if err != nil || n < 1 || n > r.opts.maxPlayers() {
+		return 0, http.StatusBadRequest
+	}
+	if r.seatHolder(n-1) != nil {
+		return 0, http.StatusConflict
+	}
+	return n - 1, 0
+}
+
+// checkSeating must be called once a player has been added to the table. The
+// table seats new players after everyone else, so if the order around the
+// table no longer matches everyone's TablePos, the table is rebuilt before
+// the next hand.
+func (r *room) checkSeating() {
+	seats := r.gameTable.Seats()
+	tableIds := make([]string, len(seats))
+	atTable := make(map[string]bool, len(seats))
+	for i, seat := range seats {
+		tableIds[i] = seat.ID
+		atTable[seat.ID] = true
+	}
+	// Players who haven't been dealt in yet aren't at the table
+	seatedIds := make([]string, 0, len(seats))
+	for _, playerId := range r.getPlayerIds() {
+		if atTable[playerId] {
+			seatedIds = append(seatedIds, playerId)
+		}
+	}
+	if !isRotation(tableIds, seatedIds) {
+		r.seatsChanged = true
+	}
+}
+
+// isRotation reports whether b goes round the table in the same order as a,
+// whoever it starts from. A rebuilt table's seats start from whoever had the
+// button, not from the lowest TablePos.
+func isRotation(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) == 0 {
+		return true
+	}
+	start := -1
+	for i, id := range b {
+		if id == a[0] {
+			start = i
+		}
+	}
+	if start < 0 {
+		return false
+	}
+	for i, id := range a {
+		if b[(start+i)%len(b)] != id {
+			return false
+		}
+	}
+	return true
+}
+
+// requestSeatChange queues a request to move to another seat, counting from
+// 1. It is granted between hands, once the seat is free.
+func (r *room) requestSeatChange(player *types.Player, seat int) {
+	if r.isTournament() {
+		r.sendError(
+			player,
+			types.ErrorCodeSeatUnavailable,
+			"Sorry, you can't change seats in a tournament.")
+		return
+	}
+	if seat < 1 || seat > r.opts.maxPlayers() {
+		r.sendError(
+			player,
+			types.ErrorCodeSeatUnavailable,
+			fmt.Sprintf("Sorry, there are only %d seats.", r.opts.maxPlayers()))
+		return
+	}
+	if player.TablePos == seat-1 {
+		r.sendError(player, types.ErrorCodeSeatUnavailable, "You're already in that seat.")
+		return
+	}
+	// A newer request replaces any earlier one
+	changes := make([]seatChange, 0, len(r.seatChanges)+1)
+	for _, change := range r.seatChanges {
+		if change.playerId != player.Id {
+			changes = append(changes, change)
+		}
+	}
+	r.seatChanges = append(changes, seatChange{playerId: player.Id, tablePos: seat - 1})
+	log.Printf("%s has asked to move to seat %d in room %s", player.Id, seat, r.id)
+	if r.betweenHands() {
+		r.processSeatChanges()
+	}
+}
+
+// processSeatChanges moves players to the seats they asked for, in the order
+// they asked, if those seats are free. Requests for taken seats wait for
+// them to free up. It must only be called between hands.
+func (r *room) processSeatChanges() {
+	pending := make([]seatChange, 0, len(r.seatChanges))
+	moved := make([]*types.Player, 0)
+	r.playerMap.Lock()
+	for _, change := range r.seatChanges {
+		player := r.playerMap.players[change.playerId]
+		if player == nil {
+			continue
+		}
+		if r.seatHolder(change.tablePos) != nil {
+			pending = append(pending, change)
+			continue
+		}
+		player.TablePos = change.tablePos
+		moved = append(moved, player)
+	}
+	r.playerMap.Unlock()
+	r.seatChanges = pending
+	if len(moved) == 0 {
+		return
+	}
+	r.seatsChanged = true
+	for _, player := range moved {
+		log.Printf("%s has moved to seat %d in room %s", player.Id, player.TablePos+1, r.id)
+		r.broadcast(types.ToPlayerMessage{
+			Type:     types.MessageTypeSeatChange,
+			PlayerId: player.Id,
+			Seat:     player.TablePos + 1,
+		})
+	}
+	r.persist()
+}
+
+// sitBackDown seats a player who left the table but stayed connected, once
+// they say they are ready, and reports whether the message should go on to
+// be handled as though they had never left.
+func (r *room) sitBackDown(msg types.FromPlayerMessage, player *types.Player) bool {
+	if msg.Type != types.MessageTypeReady {
+		log.Printf("ignoring message from %s, who has left the table", player.Id)
+		return false
+	}
+	r.playerMap.Lock()
+	if len(r.playerMap.players) >= r.opts.maxPlayers() {
+		r.playerMap.Unlock()
+		r.sendError(
+			player,
+			types.ErrorCodeSeatUnavailable,
+			"Sorry, the table is full.")
+		return false
+	}
+	player.TablePos = r.freeTablePos()
+	r.playerMap.players[player.Id] = player
+	r.playerMap.Unlock()
+	delete(r.standing, player.Id)
+	log.Printf("%s has sat back down in room %s", player.Id, r.id)
+	r.persist()
+	r.broadcast(types.ToPlayerMessage{
+		Type:     types.MessageTypePlayerConnected,
+		PlayerId: player.Id,
+	})
+	return true
+}
@@ -0,0 +1,169 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+	"github.com/gorilla/websocket"
+)
+
+func TestIsRotation(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want bool
+	}{
+		{name: "same order", a: []string{"a", "b", "c"}, b: []string{"a", "b", "c"}, want: true},
+		{name: "rotated", a: []string{"b", "c", "a", "d"}, b: []string{"a", "d", "b", "c"}, want: true},
+		{name: "out of order", a: []string{"b", "c", "a", "d"}, b: []string{"a", "b", "c", "d"}, want: false},
+		{name: "different players", a: []string{"a", "b"}, b: []string{"a", "c"}, want: false},
+		{name: "different lengths", a: []string{"a", "b"}, b: []string{"a"}, want: false},
+		{name: "empty", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRotation(tt.a, tt.b); got != tt.want {
+				t.Errorf("isRotation(%v, %v) = %t; want %t", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSeating(t *testing.T) {
+	tests := []struct {
+		name     string
+		tablePos int
+		want     bool
+	}{
+		{name: "joining after everyone", tablePos: 5, want: false},
+		{name: "joining between players", tablePos: 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+			r.playerMap.Lock()
+			r.playerMap.players["b"].TablePos = 2
+			r.playerMap.players["c"].TablePos = 4
+			r.playerMap.players["d"] = &types.Player{Id: "d", TablePos: tt.tablePos}
+			r.playerMap.Unlock()
+			sendReady(r, "d")
+			if r.seatsChanged != tt.want {
+				t.Errorf("seatsChanged = %t; want %t", r.seatsChanged, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeatChanges(t *testing.T) {
+	r := newTestRoom(t, roomOpts{MaxPlayers: 6}, "a", "b", "c")
+	// Seats can only change between hands
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	requests := []struct {
+		playerId string
+		seat     int
+	}{
+		{"a", 3}, // taken by c, so it waits
+		{"b", 6},
+		{"c", 5}, // frees seat 3 for a
+	}
+	for _, request := range requests {
+		r.handleMessageFromPlayer(
+			types.FromPlayerMessage{Type: types.MessageTypeSeatChange, Seat: request.seat},
+			r.playerMap.players[request.playerId])
+	}
+	if len(r.seatChanges) != 1 {
+		t.Fatalf("%d seat changes pending; want a's", len(r.seatChanges))
+	}
+	r.processSeatChanges()
+	want := map[string]int{"a": 2, "b": 5, "c": 4}
+	got := make(map[string]int)
+	for playerId, player := range r.playerMap.players {
+		got[playerId] = player.TablePos
+	}
+	if !reflect.DeepEqual(got, want) || len(r.seatChanges) != 0 {
+		t.Errorf("TablePos = %v with %d pending; want %v", got, len(r.seatChanges), want)
+	}
+	if !r.seatsChanged {
+		t.Error("the table wasn't marked to be rebuilt")
+	}
+}
+
+// newTestConn returns the server's end of a websocket connection to a client
+// that never reads, which is fine for the few messages a test sends it.
+func newTestConn(t *testing.T) *websocket.Conn {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		conn, err := wsUpgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	conn := <-conns
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSitBackDown(t *testing.T) {
+	r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+	// Leaving checks in the background whether the room is empty
+	r.Lock()
+	defer r.Unlock()
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	player := r.playerMap.players["b"]
+	player.Conn = newTestConn(t)
+	r.handleMessageFromPlayer(types.FromPlayerMessage{Type: types.MessageTypeLeave}, player)
+	if _, seated := r.playerMap.players["b"]; seated || r.standing["b"] != player {
+		t.Fatal("b is still seated after leaving")
+	}
+	if player.Conn == nil || r.isEmpty() {
+		t.Fatal("b was disconnected after leaving")
+	}
+	// Anything but ready is ignored until b sits back down
+	r.handleMessageFromPlayer(types.FromPlayerMessage{Type: types.MessageTypeSitOut}, player)
+	if _, seated := r.playerMap.players["b"]; seated {
+		t.Fatal("b sat back down without saying they were ready")
+	}
+	r.handleMessageFromPlayer(types.FromPlayerMessage{Type: types.MessageTypeReady}, player)
+	if r.playerMap.players["b"] != player || len(r.standing) != 0 {
+		t.Fatal("b didn't sit back down")
+	}
+	sendReady(r, "a")
+	sendReady(r, "c")
+	seat := getPlayerState("b", r.gameTable)
+	if seat.ID != "b" || seat.SittingOut || seat.Chips+seat.ChipsInPot != 100 {
+		t.Errorf("b has seat %+v; want them dealt back in for 100", seat)
+	}
+}
@@ -0,0 +1,80 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	cryptoRand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// sessionSecret is the key used to sign session tokens. If it isn't set in
+// the environment, a random one is generated, and tokens will not survive a
+// restart.
+var sessionSecret []byte
+
+func initSessionSecret() {
+	if secret := os.Getenv(SESSION_SECRET_ENV); secret != "" {
+		sessionSecret = []byte(secret)
+		return
+	}
+	sessionSecret = make([]byte, 32)
+	_, err := cryptoRand.Read(sessionSecret)
+	if err != nil {
+		log.Fatalf("error generating session secret: %s", err.Error())
+	}
+	log.Printf(
+		"warning: %s is not set; session tokens will be invalidated on restart",
+		SESSION_SECRET_ENV)
+}
+
+// newSessionToken issues a new session token for a player, which they must
+// present to reclaim their seat if they disconnect. Any previous token is
+// invalidated.
+func newSessionToken(roomId string, player *types.Player) (string, error) {
+	nonce := make([]byte, 16)
+	_, err := cryptoRand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	player.SessionNonce = base64.RawURLEncoding.EncodeToString(nonce)
+	return player.SessionNonce + "." + signSession(roomId, player.Id, player.SessionNonce), nil
+}
+
+func signSession(roomId string, playerId string, nonce string) string {
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(roomId + "\x00" + playerId + "\x00" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validSessionToken(roomId string, player *types.Player, token string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 || parts[0] != player.SessionNonce {
+		return false
+	}
+	return hmac.Equal(
+		[]byte(parts[1]),
+		[]byte(signSession(roomId, player.Id, player.SessionNonce)))
+}
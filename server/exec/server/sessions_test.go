@@ -0,0 +1,61 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestSessionToken(t *testing.T) {
+	player := &types.Player{Id: "a"}
+	stale, err := newSessionToken("room", player)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Issuing a new token invalidates the old one
+	token, err := newSessionToken("room", player)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impostor := &types.Player{Id: "b", SessionNonce: player.SessionNonce}
+	tests := []struct {
+		name   string
+		roomId string
+		player *types.Player
+		token  string
+		want   bool
+	}{
+		{name: "valid", roomId: "room", player: player, token: token, want: true},
+		{name: "stale", roomId: "room", player: player, token: stale, want: false},
+		{name: "other room", roomId: "other", player: player, token: token, want: false},
+		{name: "other player", roomId: "room", player: impostor, token: token, want: false},
+		{name: "tampered", roomId: "room", player: player, token: token + "x", want: false},
+		{name: "missing signature", roomId: "room", player: player, token: player.SessionNonce, want: false},
+		{name: "empty", roomId: "room", player: player, token: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validSessionToken(tt.roomId, tt.player, tt.token); got != tt.want {
+				t.Errorf("validSessionToken(%q, %s, %q) = %t; want %t", tt.roomId, tt.player.Id, tt.token, got, tt.want)
+			}
+		})
+	}
+}
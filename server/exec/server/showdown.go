@@ -0,0 +1,248 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"log"
+	"sort"
+
+	"github.com/alcamerone/joker/hand"
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/handEval"
+	"github.com/alcamerone/pocket2s/history"
+	"github.com/alcamerone/pocket2s/replay"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+// showdownState tracks who made the last bet or raise in the current hand,
+// and whose cards have been shown once it is over. Nobody else's cards are
+// ever sent to other players.
+type showdownState struct {
+	round          table.Round
+	aggressor      string
+	aggressorRound table.Round
+	order          []string
+	shown          map[string][]hand.Card
+}
+
+// noteAction must be called with every action made in a hand, given the
+// state before it, to keep track of the last aggressor.
+func (r *room) noteAction(playerId string, action table.Action, state table.State) {
+	r.showdown.round = state.Round
+	if isRaise(action, state) {
+		r.showdown.aggressor = playerId
+		r.showdown.aggressorRound = state.Round
+	}
+}
+
+// revealShowdown works out which cards are shown once a hand is over. The
+// last aggressor in the final betting round shows first, or the first player
+// left of the button if everyone checked, and the rest follow in turn. Each
+// player must show if their hand is at least as good as the best shown so
+// far, or if they win a pot, and otherwise mucks. If anyone is all in, every
+// hand is shown.
+func (r *room) revealShowdown(state table.State) {
+	r.showdown.shown = make(map[string][]hand.Card)
+	if len(state.Result.Contestants) < 2 {
+		return
+	}
+	order := make([]string, 0, len(state.Result.Contestants))
+	allIn := false
+	for _, c := range state.Result.Contestants {
+		order = append(order, c.ID)
+		allIn = allIn || c.AllIn
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return distanceFromButton(state, order[i]) < distanceFromButton(state, order[j])
+	})
+	if r.showdown.aggressorRound == r.showdown.round {
+		for i, playerId := range order {
+			if playerId == r.showdown.aggressor {
+				order = append(order[i:], order[:i]...)
+				break
+			}
+		}
+	}
+	r.showdown.order = order
+
+	mustShow := make(map[string]bool)
+	for _, p := range r.potResults(state) {
+		for _, playerId := range p.Winners {
+			mustShow[playerId] = true
+		}
+	}
+	var best *hand.Hand
+	for _, playerId := range order {
+		cards := seatCards(state, playerId)
+		h := handEval.BestHand(state.Options.Variant, cards, state.Result.TableCards)
+		if allIn || mustShow[playerId] || best == nil || h.CompareTo(best) >= 0 {
+			r.showdown.shown[playerId] = cards
+			if best == nil || h.CompareTo(best) > 0 {
+				best = h
+			}
+		}
+	}
+	r.recordShowdown(state)
+}
+
+// showCards shows some or all of the player's cards to the table once the
+// hand is over. Showing no cards in particular shows them all.
+func (r *room) showCards(player *types.Player, cards []hand.Card) {
+	if r.gameTable == nil || !r.betweenHands() {
+		r.sendError(
+			player,
+			types.ErrorCodeShowNotAllowed,
+			"Sorry, you can only show your cards once the hand is over.")
+		return
+	}
+	state := r.gameTable.State()
+	holeCards := seatCards(state, player.Id)
+	if len(holeCards) == 0 {
+		r.sendError(player, types.ErrorCodeShowNotAllowed, "Sorry, you weren't dealt in to that hand.")
+		return
+	}
+	if len(cards) == 0 {
+		cards = holeCards
+	}
+	show := make(map[hand.Card]bool, len(cards))
+	for _, card := range cards {
+		if !containsCard(holeCards, card) {
+			r.sendError(player, types.ErrorCodeShowNotAllowed, "Sorry, you can only show your own cards.")
+			return
+		}
+		show[card] = true
+	}
+	for _, card := range r.showdown.shown[player.Id] {
+		show[card] = true
+	}
+	shown := make([]hand.Card, 0, len(show))
+	for _, card := range holeCards {
+		if show[card] {
+			shown = append(shown, card)
+		}
+	}
+	if r.showdown.shown == nil {
+		r.showdown.shown = make(map[string][]hand.Card)
+	}
+	r.showdown.shown[player.Id] = shown
+	log.Printf("%s showed %v in room %s", player.Id, shown, r.id)
+	r.broadcast(types.ToPlayerMessage{
+		Type:        types.MessageTypeShowCards,
+		PlayerId:    player.Id,
+		Cards:       shown,
+		ShuffleSeed: r.revealSeed(state),
+	})
+	r.recordShowdown(state)
+}
+
+// recordShowdown amends the hand history with the cards shown and mucked at
+// the showdown. Cards shown when everyone else folded aren't recorded.
+func (r *room) recordShowdown(state table.State) {
+	if len(r.showdown.order) == 0 {
+		return
+	}
+	showdown := make([]history.Showdown, 0, len(r.showdown.order))
+	for _, playerId := range r.showdown.order {
+		s := history.Showdown{PlayerId: playerId, Cards: r.showdown.shown[playerId]}
+		if len(s.Cards) == 0 {
+			s.Mucked = true
+		} else if len(s.Cards) == len(seatCards(state, playerId)) {
+			s.Description = handEval.BestHand(
+				state.Options.Variant,
+				s.Cards,
+				state.Result.TableCards,
+			).Description()
+		}
+		showdown = append(showdown, s)
+	}
+	r.history.RecordShowdown(showdown)
+}
+
+// showdownResult returns the cards shown at the showdown, in the order
+// players showed or mucked.
+func (r *room) showdownResult() []types.ShownHand {
+	if len(r.showdown.order) == 0 {
+		return nil
+	}
+	shown := make([]types.ShownHand, 0, len(r.showdown.order))
+	for _, playerId := range r.showdown.order {
+		cards := r.showdown.shown[playerId]
+		shown = append(shown, types.ShownHand{
+			PlayerId: playerId,
+			Cards:    cards,
+			Mucked:   len(cards) == 0,
+		})
+	}
+	return shown
+}
+
+// cardsPublic reports whether every card dealt to a player in the finished
+// hand has been shown.
+func (r *room) cardsPublic(state table.State) bool {
+	for _, seat := range state.Seats {
+		if len(r.showdown.shown[seat.ID]) != len(seat.Cards) {
+			return false
+		}
+	}
+	return true
+}
+
+// revealSeed returns the finished hand's shuffle seed, for players to check
+// against its commitment. With CommitReveal the seed is published after every
+// hand, although it gives away mucked and folded cards. Otherwise it is kept
+// out of the table's replay log until every dealt card has been shown.
+func (r *room) revealSeed(state table.State) string {
+	if !r.opts.CommitReveal && !r.cardsPublic(state) {
+		return ""
+	}
+	seed := hex.EncodeToString(r.handSeed)
+	if r.publicSeeds == nil {
+		r.publicSeeds = make(map[string]bool)
+	}
+	r.publicSeeds[seed] = true
+	if !r.opts.CommitReveal {
+		return ""
+	}
+	return seed
+}
+
+// publicTableLog returns the replay log for the current table, without the
+// seeds of hands that haven't been revealed.
+func (r *room) publicTableLog() replay.Log {
+	l := r.tableLog.Log()
+	if !r.publicSeeds[hex.EncodeToString(l.Seed)] {
+		l.Seed = nil
+	}
+	for i, e := range l.Events {
+		if e.Type == replay.EventNewRound && !r.publicSeeds[hex.EncodeToString(e.Seed)] {
+			l.Events[i].Seed = nil
+		}
+	}
+	return l
+}
+
+func containsCard(cards []hand.Card, card hand.Card) bool {
+	for _, c := range cards {
+		if c == card {
+			return true
+		}
+	}
+	return false
+}
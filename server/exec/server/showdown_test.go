@@ -0,0 +1,73 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/randSource"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+func TestRevealSeed(t *testing.T) {
+	r := newTestRoom(t, roomOpts{CommitReveal: true}, "a", "b", "c")
+	commitment := randSource.Commitment(r.handSeed)
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	// Nobody showed, but the seed is still published to check the
+	// commitment against
+	seed, err := hex.DecodeString(r.revealSeed(r.gameTable.State()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := randSource.Commitment(seed); got != commitment {
+		t.Fatalf("revealed seed commits to %s; want %s", got, commitment)
+	}
+	if !bytes.Equal(r.publicTableLog().Seed, seed) {
+		t.Fatal("replay log is missing the revealed seed")
+	}
+}
+
+func TestWithholdSeed(t *testing.T) {
+	r := newTestRoom(t, roomOpts{}, "a", "b", "c")
+	seed := hex.EncodeToString(r.handSeed)
+	act(t, r, table.Action{Type: table.Fold}, table.Action{Type: table.Fold})
+	state := r.gameTable.State()
+	// Without commit-reveal the seed stays out of the replay log until the
+	// last player has shown
+	for i, playerId := range []string{"a", "b", "c"} {
+		if got := r.revealSeed(state); got != "" {
+			t.Fatalf("sent the seed with %d hands shown", i)
+		}
+		if r.publicTableLog().Seed != nil {
+			t.Fatalf("replay log has the seed with %d hands shown", i)
+		}
+		r.handleMessageFromPlayer(
+			types.FromPlayerMessage{Type: types.MessageTypeShowCards},
+			r.playerMap.players[playerId])
+	}
+	if r.revealSeed(state) != "" {
+		t.Fatal("sent the seed without commit-reveal")
+	}
+	if hex.EncodeToString(r.publicTableLog().Seed) != seed {
+		t.Fatal("replay log is missing the seed once every hand is shown")
+	}
+}
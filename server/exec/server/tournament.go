@@ -0,0 +1,295 @@
+/*    package "server/main" defines the Pocket2s server.
+ *    Copyright (C) 2020 Cameron Ekblad.
+ *    Email: al.camerone@gmail.com
+ *
+ *    This program is free software: you can redistribute it and/or modify
+ *    it under the terms of the GNU Affero General Public License as published
+ *    by the Free Software Foundation, either version 3 of the License, or
+ *    (at your option) any later version.
+ *
+ *    This program is distributed in the hope that it will be useful,
+ *    but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *    GNU Affero General Public License for more details.
+ *
+ *    You should have received a copy of the GNU Affero General Public License
+ *    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/alcamerone/joker/table"
+	"github.com/alcamerone/pocket2s/types"
+)
+
+type tournamentOpts struct {
+	// Levels is the blind structure. Each level lasts for the given number
+	// of hands or minutes, whichever comes first; the last level lasts until
+	// the tournament is over.
+	Levels []blindLevel
+	// EntryFee is what each entrant pays into the prize pool, and Payouts the
+	// percentage of the pool paid to each place, starting with first
+	EntryFee int
+	Payouts  []int
+}
+
+type blindLevel struct {
+	SmallBlind int
+	BigBlind   int
+	Ante       int
+	Hands      int
+	Minutes    int
+}
+
+type tournamentState struct {
+	started  bool
+	finished bool
+	entrants int
+	// entries counts the entrants plus any rebuys and add-ons, each of which
+	// pays the entry fee into the prize pool
+	entries    int
+	level      int
+	levelStart time.Time
+	levelHands int
+	// startStacks holds each player's stack at the start of the current
+	// hand, to break ties between players eliminated in the same hand
+	startStacks map[string]int
+	// standings holds eliminated players, best finish first
+	standings []types.Standing
+}
+
+type TournamentRecord struct {
+	Finished   bool
+	Entrants   int
+	Entries    int
+	Level      int
+	LevelStart time.Time
+	LevelHands int
+	Standings  []types.Standing
+}
+
+func (opts *tournamentOpts) validate() error {
+	if len(opts.Levels) == 0 {
+		return errors.New("a tournament needs at least one blind level")
+	}
+	for i, level := range opts.Levels {
+		if level.SmallBlind < 1 || level.BigBlind < level.SmallBlind || level.Ante < 0 {
+			return fmt.Errorf("invalid blinds for level %d", i+1)
+		}
+		if i < len(opts.Levels)-1 && level.Hands < 1 && level.Minutes < 1 {
+			return fmt.Errorf("level %d needs a duration in hands or minutes", i+1)
+		}
+	}
+	total := 0
+	for _, pct := range opts.Payouts {
+		if pct < 1 {
+			return errors.New("payout percentages must be positive")
+		}
+		total += pct
+	}
+	if total > 100 {
+		return errors.New("payout percentages add up to more than 100")
+	}
+	return nil
+}
+
+func (r *room) isTournament() bool {
+	return r.opts.Tournament != nil
+}
+
+// stakes returns the blinds and ante currently in play.
+func (r *room) stakes() table.Stakes {
+	if !r.isTournament() {
+		if r.isBombPot() {
+			return r.bombPotStakes()
+		}
+		return table.Stakes{
+			BigBlind:   r.opts.BigBlind,
+			SmallBlind: r.opts.SmallBlind,
+			Ante:       r.opts.Ante,
+			AnteType:   r.opts.anteType(),
+		}
+	}
+	level := r.opts.Tournament.Levels[r.tournament.level]
+	return table.Stakes{
+		BigBlind:   level.BigBlind,
+		SmallBlind: level.SmallBlind,
+		Ante:       level.Ante,
+		AnteType:   r.opts.anteType(),
+	}
+}
+
+func (r *room) blindLevel() *types.BlindLevel {
+	stakes := r.stakes()
+	return &types.BlindLevel{
+		Level:      r.tournament.level + 1,
+		SmallBlind: stakes.SmallBlind,
+		BigBlind:   stakes.BigBlind,
+		Ante:       stakes.Ante,
+	}
+}
+
+// startTournamentHand must be called once a hand has been dealt. The first
+// hand starts the tournament, after which nobody else can join.
+func (r *room) startTournamentHand(state table.State) {
+	if !r.isTournament() {
+		return
+	}
+	if !r.tournament.started {
+		r.tournament.started = true
+		r.tournament.entrants = len(r.getPlayerIds())
+		r.tournament.entries = r.tournament.entrants
+		r.tournament.levelStart = time.Now()
+		log.Printf("tournament in room %s has started with %d entrants", r.id, r.tournament.entrants)
+		r.broadcast(types.ToPlayerMessage{
+			Type:       types.MessageTypeLevelUp,
+			BlindLevel: r.blindLevel(),
+		})
+	}
+	r.tournament.startStacks = make(map[string]int, len(state.Seats))
+	for _, seat := range state.Seats {
+		r.tournament.startStacks[seat.ID] = seat.Chips + r.chipsInPot(seat)
+	}
+}
+
+// endTournamentHand must be called once a hand is over. It eliminates any
+// players who have run out of chips, finishes the tournament once there is a
+// single player left, and otherwise moves the blinds up if the level is over.
+func (r *room) endTournamentHand(state table.State) {
+	if !r.isTournament() || r.tournament.finished {
+		return
+	}
+	if r.coordinator != nil {
+		r.coordinator.endHand(r, state)
+		return
+	}
+	busted := make([]string, 0)
+	remaining := make([]string, 0)
+	for _, seat := range state.Seats {
+		if r.isEliminated(seat.ID) {
+			continue
+		}
+		// Broke players who may still rebuy stay in for now
+		if seat.Chips == 0 && !r.canRebuy(seat.ID) {
+			busted = append(busted, seat.ID)
+		} else {
+			remaining = append(remaining, seat.ID)
+		}
+	}
+	r.sortBusted(busted)
+	for i, playerId := range busted {
+		standing := r.addStanding(playerId, len(remaining)+len(busted)-i)
+		log.Printf("%s finished in place %d in room %s", playerId, standing.Place, r.id)
+		r.broadcast(types.ToPlayerMessage{
+			Type:      types.MessageTypePlayerEliminated,
+			PlayerId:  playerId,
+			Standings: []types.Standing{standing},
+		})
+	}
+	if len(remaining) <= 1 {
+		if len(remaining) == 1 {
+			r.addStanding(remaining[0], 1)
+		}
+		r.tournament.finished = true
+		log.Printf("tournament in room %s is over", r.id)
+		r.broadcast(types.ToPlayerMessage{
+			Type:      types.MessageTypeTournamentOver,
+			Standings: r.tournament.standings,
+		})
+		return
+	}
+
+	r.tournament.levelHands++
+	levels := r.opts.Tournament.Levels
+	if r.tournament.level >= len(levels)-1 {
+		return
+	}
+	level := levels[r.tournament.level]
+	handsUp := level.Hands > 0 && r.tournament.levelHands >= level.Hands
+	timeUp := level.Minutes > 0 &&
+		time.Since(r.tournament.levelStart) >= time.Duration(level.Minutes)*time.Minute
+	if !handsUp && !timeUp {
+		return
+	}
+	r.tournament.level++
+	r.tournament.levelHands = 0
+	r.tournament.levelStart = time.Now()
+	log.Printf("room %s is moving up to blind level %d", r.id, r.tournament.level+1)
+	r.broadcast(types.ToPlayerMessage{
+		Type:       types.MessageTypeLevelUp,
+		BlindLevel: r.blindLevel(),
+	})
+}
+
+func (r *room) tournamentRecord() *TournamentRecord {
+	return &TournamentRecord{
+		Finished:   r.tournament.finished,
+		Entrants:   r.tournament.entrants,
+		Entries:    r.tournament.entries,
+		Level:      r.tournament.level,
+		LevelStart: r.tournament.levelStart,
+		LevelHands: r.tournament.levelHands,
+		Standings:  r.tournament.standings,
+	}
+}
+
+// restoreTournament picks a tournament up where it was when the room was last
+// saved. Entry stays closed, since the tournament has already started.
+func (r *room) restoreTournament(record TournamentRecord) {
+	r.tournament = tournamentState{
+		started:    true,
+		finished:   record.Finished,
+		entrants:   record.Entrants,
+		entries:    record.Entries,
+		level:      record.Level,
+		levelStart: record.LevelStart,
+		levelHands: record.LevelHands,
+		standings:  record.Standings,
+	}
+}
+
+// addStanding records a player finishing in the given place, and returns
+// their standing including any payout.
+func (r *room) addStanding(playerId string, place int) types.Standing {
+	standing := types.Standing{
+		PlayerId: playerId,
+		Place:    place,
+		Payout:   r.opts.Tournament.payout(r.tournament.entries, place),
+	}
+	// Keep the standings ordered best finish first
+	r.tournament.standings = append([]types.Standing{standing}, r.tournament.standings...)
+	return standing
+}
+
+func (r *room) isEliminated(playerId string) bool {
+	for _, standing := range r.tournament.standings {
+		if standing.PlayerId == playerId {
+			return true
+		}
+	}
+	return false
+}
+
+// payout returns the prize for finishing in the given place, given the number
+// of entry fees paid.
+func (opts *tournamentOpts) payout(entries int, place int) int {
+	if place > len(opts.Payouts) {
+		return 0
+	}
+	return opts.EntryFee * entries * opts.Payouts[place-1] / 100
+}
+
+// sortBusted orders players who busted in the same hand from worst finish to
+// best: whoever started the hand with more chips finishes higher.
+func (r *room) sortBusted(busted []string) {
+	sort.Slice(busted, func(i, j int) bool {
+		return r.tournament.startStacks[busted[i]] < r.tournament.startStacks[busted[j]]
+	})
+}
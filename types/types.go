@@ -19,6 +19,9 @@
 package types
 
 import (
+	"time"
+
+	"github.com/alcamerone/joker/hand"
 	"github.com/alcamerone/joker/table"
 	"github.com/gorilla/websocket"
 )
@@ -36,8 +39,150 @@ const (
 	MessageTypeIllegalAction
 	MessageTypePlayerConnected
 	MessageTypePlayerDisconnected
+	MessageTypeShuffleCommitment
+	MessageTypeError
+	MessageTypeLevelUp
+	MessageTypePlayerEliminated
+	MessageTypeTournamentOver
+	MessageTypeTableChange
+	MessageTypeAddOn
+	MessageTypeTopUp
+	MessageTypeLeave
+	MessageTypeSeatChange
+	MessageTypeRunItTwice
+	MessageTypeStraddle
+	MessageTypeShowCards
+	MessageTypeTimeBank
+)
+
+type ErrorCode int
+
+const (
+	ErrorCodeUnknown ErrorCode = iota
+	ErrorCodeInternal
+	ErrorCodeUnknownMessageType
+	ErrorCodeNoGameInProgress
+	ErrorCodeNotYourTurn
+	ErrorCodeBuyInFailed
+	ErrorCodeBetOutOfRange
+	ErrorCodeRebuyNotAllowed
+	ErrorCodeTournamentOver
+	ErrorCodeRebuyLimitReached
+	ErrorCodeAddOnNotAllowed
+	ErrorCodeBuyInOutOfRange
+	ErrorCodeLeaveNotAllowed
+	ErrorCodeSeatUnavailable
+	ErrorCodeStraddleNotAllowed
+	ErrorCodeShowNotAllowed
+)
+
+type Limit int
+
+const (
+	LimitNoLimit Limit = iota
+	LimitPotLimit
+	LimitFixedLimit
 )
 
+func (l Limit) String() string {
+	switch l {
+	case LimitNoLimit:
+		return "No Limit"
+	case LimitPotLimit:
+		return "Pot Limit"
+	case LimitFixedLimit:
+		return "Fixed Limit"
+	}
+	return "Unknown Limit"
+}
+
+// AnteType is who posts the ante: everyone, or just the big blind or button
+// on everyone's behalf
+type AnteType int
+
+const (
+	AnteTypeClassic AnteType = iota
+	AnteTypeBigBlind
+	AnteTypeButton
+)
+
+func (a AnteType) String() string {
+	switch a {
+	case AnteTypeClassic:
+		return "Ante"
+	case AnteTypeBigBlind:
+		return "Big Blind Ante"
+	case AnteTypeButton:
+		return "Button Ante"
+	}
+	return "Unknown Ante"
+}
+
+// Game is a variant and betting structure a room may play
+type Game struct {
+	Variant table.Variant
+	Limit   Limit
+}
+
+func (g Game) String() string {
+	if g.Variant == table.OmahaHi {
+		return g.Limit.String() + " Omaha"
+	}
+	return g.Limit.String() + " Texas Hold'em"
+}
+
+type BlindLevel struct {
+	Level      int
+	SmallBlind int
+	BigBlind   int
+	Ante       int
+}
+
+// Standing is where a player finished in a tournament, and what they won
+type Standing struct {
+	PlayerId string
+	Place    int
+	Payout   int
+}
+
+// RaiseLimits is the range of chips the active player may bet or raise by,
+// on top of what they owe. A maximum of zero means they may not raise.
+// AllIn is false if going all in would break the limit.
+type RaiseLimits struct {
+	Min   int
+	Max   int
+	AllIn bool
+}
+
+// PotResult is who won a pot, or the share of a pot dealt on one board if
+// the board was run more than once
+type PotResult struct {
+	Amount   int
+	Eligible []string
+	Winners  []string
+	// Board is the board the pot was won on, counting from 0
+	Board int `json:",omitempty"`
+	// Ranking and Hand are the ranking and description of the winning hand,
+	// and are empty if everyone else folded
+	Ranking hand.Ranking `json:",omitempty"`
+	Hand    string       `json:",omitempty"`
+	// Awarded is the chips each winner took from the pot
+	Awarded map[string]int
+}
+
+// ShownHand is what a player showed at the showdown. Mucked hands have no
+// cards.
+type ShownHand struct {
+	PlayerId string
+	Cards    []hand.Card `json:",omitempty"`
+	Mucked   bool        `json:",omitempty"`
+}
+
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
 type Player struct {
 	Id         string
 	Conn       *websocket.Conn
@@ -45,11 +190,26 @@ type Player struct {
 	Ready      bool
 	SittingOut bool
 	Broke      bool
+	TimeBank   int
+	// SessionNonce identifies the player's current session token
+	SessionNonce string
+	// RoomId is the room the player is seated in, which can change during a
+	// multi-table tournament
+	RoomId string
 }
 
 type FromPlayerMessage struct {
 	Type   MessageType
 	Action table.Action
+	// Amount is the number of chips to buy in or top up with. Zero buys in
+	// for the room's default amount.
+	Amount int `json:",omitempty"`
+	// Seat is the seat asked for with a seat change, counting from 1
+	Seat int `json:",omitempty"`
+	// Agree answers an offer to run the board more than once
+	Agree bool `json:",omitempty"`
+	// Cards are the cards to show once a hand is over, all of them if empty
+	Cards []hand.Card `json:",omitempty"`
 }
 
 type ToPlayerMessage struct {
@@ -59,6 +219,61 @@ type ToPlayerMessage struct {
 	PlayerState  table.Player `json:",omitempty"`
 	PlayerAction PlayerAction `json:",omitempty"`
 	Result       string       `json:",omitempty"`
+	// ActionDeadline is the time by which the active player must act before
+	// the server acts on their behalf. It is zero if there is no time limit.
+	// It is sent again with the "time bank" message when the active player
+	// starts drawing on their time bank.
+	ActionDeadline time.Time `json:",omitempty"`
+	// TimeBank is the number of seconds the receiving player has left in
+	// their time bank
+	TimeBank int `json:",omitempty"`
+	// ShuffleCommitment is the hex-encoded SHA-256 hash of the seed the next
+	// hand will be shuffled with, and ShuffleSeed the hex-encoded seed itself,
+	// revealed once the hand is over. The seed gives away every card dealt,
+	// including those mucked or folded
+	ShuffleCommitment string `json:",omitempty"`
+	ShuffleSeed       string `json:",omitempty"`
+	// SessionToken is sent with the "hello" message, and must be presented to
+	// reconnect as the same player
+	SessionToken string       `json:",omitempty"`
+	Error        *Error       `json:",omitempty"`
+	RaiseLimits  *RaiseLimits `json:",omitempty"`
+	Game         *Game        `json:",omitempty"`
+	BlindLevel   *BlindLevel  `json:",omitempty"`
+	Standings    []Standing   `json:",omitempty"`
+	// RoomId is sent with the "table change" message when a player is moved
+	// to another table in a multi-table tournament
+	RoomId string `json:",omitempty"`
+	// AddOn is sent with the result of the hand that closes the rebuy window,
+	// and is the number of chips each player may now buy once
+	AddOn int `json:",omitempty"`
+	// CashOut is sent with the "leave" message to the player who has left,
+	// and is the stack they left with
+	CashOut int `json:",omitempty"`
+	// Seat is sent with the "seat change" message, and is the seat the player
+	// has moved to, counting from 1
+	Seat int `json:",omitempty"`
+	// RunItTimes is sent with an offer to run the board more than once, and
+	// is the number of boards that will be dealt if everyone agrees. Boards
+	// then holds every board dealt, with the hand's result.
+	RunItTimes int           `json:",omitempty"`
+	Boards     [][]hand.Card `json:",omitempty"`
+	// BombPot is set on table states during a bomb pot
+	BombPot bool `json:",omitempty"`
+	// Antes is sent with table states, and is the ante each player posted in
+	// the current hand
+	Antes map[string]int `json:",omitempty"`
+	// Showdown is sent with the result of a hand that went to showdown, in
+	// the order players showed or mucked. Cards is sent with the "show cards"
+	// message, and is the cards the player has chosen to show.
+	Showdown []ShownHand `json:",omitempty"`
+	Cards    []hand.Card `json:",omitempty"`
+	// Pots is sent with the result of a hand, and breaks down who won what
+	// from each pot, board by board if the board was run more than once
+	Pots []PotResult `json:",omitempty"`
+	// Returned is sent with the result of a hand, and is the uncalled bet
+	// handed back to whoever made it, which isn't part of any pot
+	Returned map[string]int `json:",omitempty"`
 }
 
 type PlayerAction struct {